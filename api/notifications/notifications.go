@@ -0,0 +1,21 @@
+package api_notifications
+
+import (
+	"net/http"
+
+	"github.com/wavix/w-alerts/notifier"
+
+	"github.com/gin-gonic/gin"
+)
+
+type NotificationsController struct{}
+
+func NewController() NotificationsController {
+	return NotificationsController{}
+}
+
+func (controller NotificationsController) GetDeadLetters(context *gin.Context) {
+	pending, deadLetters := notifier.DeadLetters()
+
+	context.JSON(http.StatusOK, gin.H{"success": true, "pending": pending, "dead_letters": deadLetters})
+}
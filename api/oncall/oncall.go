@@ -0,0 +1,97 @@
+package api_oncall
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/wavix/w-alerts/notifier"
+	"github.com/wavix/w-alerts/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type OverrideCreationPayload struct {
+	Schedule  string     `json:"schedule" binding:"required"`
+	Member    string     `json:"member" binding:"required"`
+	Start     *time.Time `json:"start"`
+	End       *time.Time `json:"end"`
+	Duration  string     `json:"duration"`
+	CreatedBy string     `json:"created_by" binding:"required"`
+}
+
+type OnCallController struct{}
+
+func NewController() OnCallController {
+	return OnCallController{}
+}
+
+func (controller OnCallController) GetOnCall(context *gin.Context) {
+	context.JSON(http.StatusOK, gin.H{"success": true, "oncall": notifier.ListOnCall()})
+}
+
+func (controller OnCallController) GetOverrides(context *gin.Context) {
+	context.JSON(http.StatusOK, gin.H{"success": true, "overrides": notifier.ListOverrides()})
+}
+
+func (controller OnCallController) AddOverride(context *gin.Context) {
+	var payload OverrideCreationPayload
+
+	if !utils.ValidateBody(context, &payload) {
+		return
+	}
+
+	override := notifier.Override{
+		Schedule:  payload.Schedule,
+		Member:    payload.Member,
+		CreatedBy: payload.CreatedBy,
+	}
+
+	if payload.Start != nil {
+		override.Start = payload.Start.UTC()
+	} else {
+		override.Start = time.Now().UTC()
+	}
+
+	if payload.End != nil {
+		override.End = payload.End.UTC()
+	} else if payload.Duration != "" {
+		duration, err := time.ParseDuration(payload.Duration)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid duration"})
+			return
+		}
+
+		override.End = override.Start.Add(duration)
+	} else {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "end or duration is required"})
+		return
+	}
+
+	created, err := notifier.AddOverride(override)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	utils.Logger.Info().Msgf("On-call override %s of '%s' created by %s: %s until %v", created.ID, created.Schedule, created.CreatedBy, created.Member, created.End)
+	context.JSON(http.StatusOK, gin.H{"success": true, "message": "Override created", "override": created})
+}
+
+func (controller OnCallController) RemoveOverride(context *gin.Context) {
+	id := context.Param("id")
+
+	err := notifier.RemoveOverride(id)
+	if errors.Is(err, notifier.ErrOverrideNotFound) {
+		context.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Override not found"})
+		return
+	}
+
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	utils.Logger.Info().Msgf("On-call override %s removed", id)
+	context.JSON(http.StatusOK, gin.H{"success": true, "message": "Override removed"})
+}
@@ -1,22 +1,37 @@
 package api
 
 import (
+	api_notifications "github.com/wavix/w-alerts/api/notifications"
+	api_oncall "github.com/wavix/w-alerts/api/oncall"
+	api_routes "github.com/wavix/w-alerts/api/routes"
 	api_rules "github.com/wavix/w-alerts/api/rules"
+	api_silences "github.com/wavix/w-alerts/api/silences"
 	api_status "github.com/wavix/w-alerts/api/status"
+	api_voice "github.com/wavix/w-alerts/api/voice"
 	"github.com/wavix/w-alerts/rule"
 
 	"github.com/gin-gonic/gin"
 )
 
 type Controllers struct {
-	statusController api_status.StatusController
-	rulesController  api_rules.RulesController
+	statusController        api_status.StatusController
+	rulesController         api_rules.RulesController
+	voiceController         api_voice.VoiceController
+	routesController        api_routes.RoutesController
+	notificationsController api_notifications.NotificationsController
+	silencesController      api_silences.SilencesController
+	onCallController        api_oncall.OnCallController
 }
 
 func NewControllers(register *rule.Registry) *Controllers {
 	return &Controllers{
-		statusController: api_status.NewController(register),
-		rulesController:  api_rules.NewController(register),
+		statusController:        api_status.NewController(register),
+		rulesController:         api_rules.NewController(register),
+		voiceController:         api_voice.NewController(register),
+		routesController:        api_routes.NewController(register),
+		notificationsController: api_notifications.NewController(),
+		silencesController:      api_silences.NewController(),
+		onCallController:        api_oncall.NewController(),
 	}
 }
 
@@ -25,4 +40,16 @@ func (controllers *Controllers) Routes(router *gin.Engine) {
 	routes.GET("/status", controllers.statusController.GetStatus)
 	routes.POST("/api/rules", controllers.rulesController.AddRule)
 	routes.PATCH("/api/rules", controllers.rulesController.UpdateRule)
+	routes.POST("/api/rules/:uuid/ack", controllers.rulesController.AckRule)
+	routes.DELETE("/api/rules/:uuid/ack", controllers.rulesController.UnackRule)
+	routes.POST("/api/voice/callback", controllers.voiceController.Callback)
+	routes.GET("/api/routes/test", controllers.routesController.TestRoute)
+	routes.GET("/api/notifications/dead-letters", controllers.notificationsController.GetDeadLetters)
+	routes.GET("/api/silences", controllers.silencesController.GetSilences)
+	routes.POST("/api/silences", controllers.silencesController.AddSilence)
+	routes.DELETE("/api/silences/:id", controllers.silencesController.ExpireSilence)
+	routes.GET("/api/oncall", controllers.onCallController.GetOnCall)
+	routes.GET("/api/oncall/overrides", controllers.onCallController.GetOverrides)
+	routes.POST("/api/oncall/overrides", controllers.onCallController.AddOverride)
+	routes.DELETE("/api/oncall/overrides/:id", controllers.onCallController.RemoveOverride)
 }
@@ -0,0 +1,64 @@
+package api_routes
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wavix/w-alerts/notifier"
+	"github.com/wavix/w-alerts/rule"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RouteTestQuery struct {
+	UUID     string `form:"uuid"`
+	Name     string `form:"name"`
+	Scope    string `form:"scope"`
+	File     string `form:"file"`
+	Severity string `form:"severity"`
+}
+
+type RoutesController struct {
+	registry *rule.Registry
+}
+
+func NewController(registry *rule.Registry) RoutesController {
+	return RoutesController{
+		registry: registry,
+	}
+}
+
+// TestRoute returns the receivers which would get the notifications of the rule.
+// The rule is found by uuid, otherwise it's described by the query parameters
+func (controller RoutesController) TestRoute(context *gin.Context) {
+	var query RouteTestQuery
+
+	if err := context.ShouldBindQuery(&query); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "error": "bad request"})
+		return
+	}
+
+	event := notifier.Event{
+		Status:   notifier.StatusFiring,
+		UUID:     query.UUID,
+		Name:     query.Name,
+		Scope:    &query.Scope,
+		File:     query.File,
+		Severity: query.Severity,
+	}
+
+	if query.UUID != "" {
+		controller.registry.Mutex.RLock()
+		rule, exists := controller.registry.Rules[query.UUID]
+		controller.registry.Mutex.RUnlock()
+
+		if !exists {
+			context.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Rule not found"})
+			return
+		}
+
+		event = rule.NotificationEvent(time.Now().UTC())
+	}
+
+	context.JSON(http.StatusOK, gin.H{"success": true, "receivers": notifier.Receivers(event)})
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/wavix/w-alerts/rule"
+	"github.com/wavix/w-alerts/types"
 	"github.com/wavix/w-alerts/utils"
 
 	"github.com/gin-gonic/gin"
@@ -23,6 +24,12 @@ type RuleUpdatePayload struct {
 	IsFire bool   `json:"is_fire"`
 }
 
+type RuleAckPayload struct {
+	By        string `json:"by" binding:"required"`
+	Comment   string `json:"comment"`
+	ExpiresIn string `json:"expires_in"`
+}
+
 type RulesController struct {
 	registry *rule.Registry
 }
@@ -42,9 +49,16 @@ func (controller RulesController) AddRule(context *gin.Context) {
 
 	now := time.Now().UTC()
 	isFire := payload.IsFire
+	wasFire := false
+	var ack *types.Acknowledgement
 
-	if _, exists := controller.registry.Rules[payload.UUID]; exists {
-		controller.registry.RemoveRule(payload.UUID)
+	if current, exists := controller.registry.Rules[payload.UUID]; exists {
+		wasFire = current.IsFire
+		ack = current.Ack
+	}
+
+	if !isFire {
+		ack = nil
 	}
 
 	newRule := rule.Rule{
@@ -55,9 +69,23 @@ func (controller RulesController) AddRule(context *gin.Context) {
 		LastExecuted:  &now,
 		IsFire:        isFire,
 		IsStaticAlert: true,
+		Ack:           ack,
+	}
+
+	if err := newRule.CompileDescription(); err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"success": "false", "message": err.Error()})
+		return
 	}
 
+	controller.registry.RemoveRule(payload.UUID)
+
 	controller.registry.AddRule(newRule)
+	controller.registry.CheckInhibition(controller.registry.Rules[payload.UUID])
+
+	if isFire != wasFire {
+		controller.registry.Rules[payload.UUID].NotifyTransition()
+	}
+
 	controller.registry.SaveStaticRules()
 
 	logger := utils.Logger.Info()
@@ -83,9 +111,72 @@ func (controller RulesController) UpdateRule(context *gin.Context) {
 	}
 
 	rule := controller.registry.Rules[payload.UUID]
-	rule.IsFire = payload.IsFire
+	controller.registry.CheckInhibition(rule)
+	rule.SetFire(payload.IsFire)
 
 	controller.registry.SaveStaticRules()
 
 	context.JSON(http.StatusOK, gin.H{"success": "true", "message": "Rule successfully updated"})
 }
+
+func (controller RulesController) AckRule(context *gin.Context) {
+	var payload RuleAckPayload
+
+	if !utils.ValidateBody(context, &payload) {
+		return
+	}
+
+	rule, exists := controller.registry.Rules[context.Param("uuid")]
+	if !exists {
+		context.JSON(http.StatusNotFound, gin.H{"success": "false", "message": "Rule not found"})
+		return
+	}
+
+	if !rule.IsFire {
+		context.JSON(http.StatusBadRequest, gin.H{"success": "false", "message": "Rule is not firing"})
+		return
+	}
+
+	ack := types.Acknowledgement{
+		By:      payload.By,
+		Comment: payload.Comment,
+		At:      time.Now().UTC(),
+	}
+
+	if payload.ExpiresIn != "" {
+		duration, err := time.ParseDuration(payload.ExpiresIn)
+		if err != nil || duration <= 0 {
+			context.JSON(http.StatusBadRequest, gin.H{"success": "false", "message": "Invalid expires_in duration"})
+			return
+		}
+
+		expiresAt := ack.At.Add(duration)
+		ack.ExpiresAt = &expiresAt
+	}
+
+	rule.Acknowledge(ack)
+
+	if rule.IsStaticAlert {
+		controller.registry.SaveStaticRules()
+	}
+
+	utils.Logger.Context(rule.Name).Info().Msgf("Rule acknowledged by %s", payload.By)
+	context.JSON(http.StatusOK, gin.H{"success": "true", "message": "Rule acknowledged", "ack": ack})
+}
+
+func (controller RulesController) UnackRule(context *gin.Context) {
+	rule, exists := controller.registry.Rules[context.Param("uuid")]
+	if !exists {
+		context.JSON(http.StatusNotFound, gin.H{"success": "false", "message": "Rule not found"})
+		return
+	}
+
+	rule.Unacknowledge()
+
+	if rule.IsStaticAlert {
+		controller.registry.SaveStaticRules()
+	}
+
+	utils.Logger.Context(rule.Name).Info().Msg("Rule acknowledgement removed")
+	context.JSON(http.StatusOK, gin.H{"success": "true", "message": "Rule acknowledgement removed"})
+}
@@ -0,0 +1,87 @@
+package api_silences
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/wavix/w-alerts/notifier"
+	"github.com/wavix/w-alerts/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SilenceCreationPayload struct {
+	Match     map[string]string `json:"match"`
+	MatchRe   map[string]string `json:"match_re"`
+	StartsAt  *time.Time        `json:"starts_at"`
+	EndsAt    *time.Time        `json:"ends_at"`
+	Duration  string            `json:"duration"`
+	CreatedBy string            `json:"created_by" binding:"required"`
+	Comment   string            `json:"comment"`
+}
+
+type SilencesController struct{}
+
+func NewController() SilencesController {
+	return SilencesController{}
+}
+
+func (controller SilencesController) GetSilences(context *gin.Context) {
+	context.JSON(http.StatusOK, gin.H{"success": true, "silences": notifier.ListSilences()})
+}
+
+func (controller SilencesController) AddSilence(context *gin.Context) {
+	var payload SilenceCreationPayload
+
+	if !utils.ValidateBody(context, &payload) {
+		return
+	}
+
+	silence := notifier.Silence{
+		Matchers:  notifier.Matchers{Match: payload.Match, MatchRe: payload.MatchRe},
+		CreatedBy: payload.CreatedBy,
+		Comment:   payload.Comment,
+	}
+
+	if payload.StartsAt != nil {
+		silence.StartsAt = payload.StartsAt.UTC()
+	} else {
+		silence.StartsAt = time.Now().UTC()
+	}
+
+	if payload.EndsAt != nil {
+		silence.EndsAt = payload.EndsAt.UTC()
+	} else if payload.Duration != "" {
+		duration, err := time.ParseDuration(payload.Duration)
+		if err != nil {
+			context.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "Invalid duration"})
+			return
+		}
+
+		silence.EndsAt = silence.StartsAt.Add(duration)
+	} else {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "ends_at or duration is required"})
+		return
+	}
+
+	created, err := notifier.AddSilence(silence)
+	if err != nil {
+		context.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	utils.Logger.Info().Msgf("Silence %s created by %s until %v", created.ID, created.CreatedBy, created.EndsAt)
+	context.JSON(http.StatusOK, gin.H{"success": true, "message": "Silence created", "silence": created})
+}
+
+func (controller SilencesController) ExpireSilence(context *gin.Context) {
+	id := context.Param("id")
+
+	if !notifier.ExpireSilence(id) {
+		context.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Silence not found"})
+		return
+	}
+
+	utils.Logger.Info().Msgf("Silence %s expired", id)
+	context.JSON(http.StatusOK, gin.H{"success": true, "message": "Silence expired"})
+}
@@ -1,11 +1,12 @@
 package api_status
 
 import (
-	"fmt"
 	"net/http"
-	"strings"
+	"time"
 
+	"github.com/wavix/w-alerts/notifier"
 	"github.com/wavix/w-alerts/rule"
+	"github.com/wavix/w-alerts/types"
 	"github.com/wavix/w-alerts/utils"
 
 	"github.com/gin-gonic/gin"
@@ -15,11 +16,30 @@ type StatusController struct {
 	registry *rule.Registry
 }
 
+type RuleInhibition struct {
+	UUID string `json:"uuid"`
+	Name string `json:"name"`
+}
+
 type RuleStatus struct {
 	UUID        string `json:"uuid"`
 	Name        string `json:"name"`
 	Description string `json:"description"`
 	Status      string `json:"status"`
+
+	PendingSince *time.Time `json:"pending_since"`
+	FlapScore    float64    `json:"flap_score"`
+	DataState    string     `json:"data_state"` // no_data or error when the last evaluation has failed
+	LastError    *string    `json:"last_error"`
+	LastErrorAt  *time.Time `json:"last_error_at"`
+
+	Ack         *types.Acknowledgement     `json:"ack"`
+	Silenced    bool                       `json:"silenced"` // the rule is firing and silenced
+	SilencedBy  *string                    `json:"silenced_by"`
+	Maintenance bool                       `json:"maintenance"`
+	Inhibited   bool                       `json:"inhibited"` // the rule is firing and inhibited
+	InhibitedBy *RuleInhibition            `json:"inhibited_by"`
+	Escalation  *notifier.EscalationStatus `json:"escalation"`
 }
 
 func NewController(registry *rule.Registry) StatusController {
@@ -30,27 +50,46 @@ func NewController(registry *rule.Registry) StatusController {
 
 func (controller StatusController) GetStatus(context *gin.Context) {
 	response := make([]RuleStatus, 0)
+	now := time.Now().UTC()
 
 	for _, rule := range controller.registry.Rules {
-		status := "ok"
+		var silencedBy *string
+		if silence := notifier.Silenced(rule.MatchEvent()); silence != nil {
+			id := silence.ID
+			silencedBy = &id
+		}
 
-		if rule.IsFire {
-			status = "problem"
+		var inhibitedBy *RuleInhibition
+		if source := controller.registry.InhibitedBy(rule); source != nil {
+			inhibitedBy = &RuleInhibition{UUID: source.UUID, Name: utils.ScopedName(source.Name, source.Scope)}
 		}
 
-		description := utils.ReplacePlaceholders(rule.Description, rule.RulesResults)
+		description := rule.RenderDescription()
 
-		name := rule.Name
+		name := utils.ScopedName(rule.Name, rule.Scope)
 
-		if rule.Scope != nil && *rule.Scope != "" {
-			name = fmt.Sprintf("[%s] %s", strings.ToUpper(*rule.Scope), rule.Name)
+		var ack *types.Acknowledgement
+		if rule.Ack.IsActive() {
+			ack = rule.Ack
 		}
 
 		response = append(response, RuleStatus{
-			UUID:        rule.UUID,
-			Name:        name,
-			Description: description,
-			Status:      status,
+			UUID:         rule.UUID,
+			Name:         name,
+			Description:  description,
+			Status:       rule.Status(),
+			PendingSince: rule.PendingSince,
+			FlapScore:    rule.FlapScore,
+			DataState:    rule.DataState,
+			LastError:    rule.LastError,
+			LastErrorAt:  rule.LastErrorAt,
+			Ack:          ack,
+			Silenced:     rule.IsFire && silencedBy != nil,
+			SilencedBy:   silencedBy,
+			Maintenance:  controller.registry.ActiveMaintenance(rule, now) != nil,
+			Inhibited:    rule.IsFire && inhibitedBy != nil,
+			InhibitedBy:  inhibitedBy,
+			Escalation:   notifier.Escalation(rule.UUID),
 		})
 	}
 
@@ -0,0 +1,71 @@
+package api_voice
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/wavix/w-alerts/notifier"
+	"github.com/wavix/w-alerts/rule"
+	"github.com/wavix/w-alerts/types"
+	"github.com/wavix/w-alerts/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CallbackPayload struct {
+	UUID     string `json:"uuid" form:"uuid" binding:"required"`
+	Receiver string `json:"receiver" form:"receiver"`
+	Digits   string `json:"digits" form:"digits" binding:"required"`
+	Token    string `json:"token" form:"token"`
+}
+
+type VoiceController struct {
+	registry *rule.Registry
+}
+
+func NewController(registry *rule.Registry) VoiceController {
+	return VoiceController{
+		registry: registry,
+	}
+}
+
+func (controller VoiceController) Callback(context *gin.Context) {
+	var payload CallbackPayload
+
+	// uuid, receiver and token are passed in the callback url, the digits
+	// are passed either in the query string or in the JSON body
+	_ = context.ShouldBindQuery(&payload)
+	token := payload.Token
+
+	if payload.UUID == "" || payload.Digits == "" {
+		if !utils.ValidateBody(context, &payload) {
+			return
+		}
+	}
+
+	if token == "" {
+		token = payload.Token
+	}
+
+	if token == "" {
+		context.JSON(http.StatusUnauthorized, gin.H{"success": "false", "message": "Token is required"})
+		return
+	}
+
+	if !notifier.AcknowledgeCall(payload.UUID, payload.Receiver, payload.Digits, token) {
+		context.JSON(http.StatusOK, gin.H{"success": "true", "acknowledged": false})
+		return
+	}
+
+	if rule, exists := controller.registry.Rules[payload.UUID]; exists && rule.IsFire {
+		rule.Acknowledge(types.Acknowledgement{
+			By:      fmt.Sprintf("voice:%s", payload.Receiver),
+			Comment: "Acknowledged by phone",
+			At:      time.Now().UTC(),
+		})
+	}
+
+	utils.Logger.Context(payload.Receiver).Info().Msgf("Voice call acknowledged (UUID: %s)", payload.UUID)
+	context.JSON(http.StatusOK, gin.H{"success": "true", "acknowledged": true})
+}
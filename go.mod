@@ -3,6 +3,8 @@ module github.com/wavix/w-alerts
 go 1.21.13
 
 require (
+	github.com/expr-lang/expr v1.16.9
+	github.com/go-playground/validator/v10 v10.20.0
 	github.com/mitchellh/mapstructure v1.5.0
 	github.com/wavix/go-lib v0.0.13
 )
@@ -16,7 +18,6 @@ require (
 	github.com/gin-contrib/sse v0.1.0 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.20.0 // indirect
 	github.com/goccy/go-json v0.10.2 // indirect
 	github.com/json-iterator/go v1.1.12 // indirect
 	github.com/klauspost/cpuid/v2 v2.2.7 // indirect
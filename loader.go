@@ -6,7 +6,9 @@ import (
 	"io"
 	"os"
 	"slices"
+	"strconv"
 
+	"github.com/wavix/w-alerts/notifier"
 	"github.com/wavix/w-alerts/rule"
 	"github.com/wavix/w-alerts/utils"
 
@@ -60,6 +62,89 @@ func loadRules(registry *rule.Registry) {
 	registry.Mutex.Unlock()
 }
 
+func loadMaintenance(registry *rule.Registry) {
+	path := os.Getenv("MAINTENANCE_FILE")
+	if path == "" {
+		return
+	}
+
+	err := registry.LoadMaintenance(path)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error loading maintenance windows: %v", err)
+		os.Exit(1)
+	}
+
+	utils.Logger.Info().Msgf("Maintenance windows loaded from %v", path)
+}
+
+func loadFlapDetection() {
+	settings := rule.FlapDefaults
+	var err error
+
+	if value := os.Getenv("FLAP_DETECTION"); value != "" {
+		settings.Enabled, err = strconv.ParseBool(value)
+	}
+
+	if value := os.Getenv("FLAP_HISTORY"); value != "" && err == nil {
+		settings.History, err = strconv.Atoi(value)
+	}
+
+	if value := os.Getenv("FLAP_LOW_THRESHOLD"); value != "" && err == nil {
+		settings.LowThreshold, err = strconv.ParseFloat(value, 64)
+	}
+
+	if value := os.Getenv("FLAP_HIGH_THRESHOLD"); value != "" && err == nil {
+		settings.HighThreshold, err = strconv.ParseFloat(value, 64)
+	}
+
+	if err == nil {
+		err = settings.Validate()
+	}
+
+	if err != nil {
+		utils.Logger.Error().Msgf("Error loading flap detection settings: %v", err)
+		os.Exit(1)
+	}
+
+	rule.FlapDefaults = settings
+}
+
+func loadInhibitRules(registry *rule.Registry) {
+	path := os.Getenv("INHIBIT_FILE")
+	if path == "" {
+		return
+	}
+
+	err := registry.LoadInhibitRules(path)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error loading inhibit rules: %v", err)
+		os.Exit(1)
+	}
+
+	notifier.SetInhibitor(registry.Inhibitor)
+
+	utils.Logger.Info().Msgf("Inhibit rules loaded from %v", path)
+}
+
+func loadNotifiers() {
+	err := notifier.Setup(notifier.SetupOptions{
+		ConfigFile:    os.Getenv("NOTIFIERS_FILE"),
+		RoutesFile:    os.Getenv("ROUTES_FILE"),
+		OutboxFile:    filepath.Join(os.Getenv("STATIC_RULES_DIR"), "outbox.json"),
+		OverridesFile: filepath.Join(os.Getenv("STATIC_RULES_DIR"), "oncall-overrides.json"),
+	})
+	if err != nil {
+		utils.Logger.Error().Msgf("Error loading notifiers: %v", err)
+		os.Exit(1)
+	}
+
+	err = notifier.LoadSilences(filepath.Join(os.Getenv("STATIC_RULES_DIR"), "silences.json"))
+	if err != nil {
+		utils.Logger.Error().Msgf("Error loading silences: %v", err)
+		os.Exit(1)
+	}
+}
+
 func loadRule(path string) (*[]rule.Rule, error) {
 	jsonFile, err := os.Open(path)
 	if err != nil {
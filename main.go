@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/wavix/w-alerts/api"
+	"github.com/wavix/w-alerts/notifier"
 	"github.com/wavix/w-alerts/requests"
 	"github.com/wavix/w-alerts/rule"
 	"github.com/wavix/w-alerts/types"
@@ -31,7 +32,11 @@ func main() {
 		Mutex: sync.RWMutex{},
 	}
 
+	loadFlapDetection()
 	loadRules(&registry)
+	loadMaintenance(&registry)
+	loadInhibitRules(&registry)
+	loadNotifiers()
 	registry.LoadStaticRules()
 
 	go process(&registry)
@@ -57,24 +62,34 @@ func ticker(registry *rule.Registry, done chan bool) {
 }
 
 func process(registry *rule.Registry) {
-	for _, rule := range registry.Rules {
-		if rule.IsStaticAlert {
-			continue
+	// The notifications are sent once all the rules are processed,
+	// so the inhibition doesn't depend on the order of the rules
+	notifier.Batch(func() {
+		for _, rule := range registry.Rules {
+			if rule.IsStaticAlert {
+				continue
+			}
+
+			if registry.CheckMaintenance(rule, time.Now()) {
+				continue
+			}
+
+			nextRunTimer := rule.GetNextRunAt()
+			if nextRunTimer.After(time.Now()) {
+				continue
+			}
+
+			result, err := execRule(rule)
+			if err != nil {
+				rule.RecordError(fmt.Errorf("error executing rule: %w", err))
+				continue
+			}
+
+			rule.ProcessResponse(*result)
 		}
 
-		nextRunTimer := rule.GetNextRunAt()
-		if nextRunTimer.After(time.Now()) {
-			continue
-		}
-
-		result, err := execRule(rule)
-		if err != nil {
-			utils.Logger.Error().Msgf("Error executing rule: %v", err)
-			continue
-		}
-
-		rule.ProcessResponse(*result)
-	}
+		registry.CheckInhibitions()
+	})
 }
 
 func execRule(rule *rule.Rule) (*types.RuleResponse, error) {
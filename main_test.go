@@ -2,9 +2,19 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/go-playground/assert"
+	"github.com/wavix/w-alerts/notifier"
 	"github.com/wavix/w-alerts/rule"
 	"github.com/wavix/w-alerts/utils"
 )
@@ -124,3 +134,631 @@ func TestElasticSearchQueryMatchPhrase(t *testing.T) {
 
 	assert.Equal(t, string(output), utils.JsonFormat(outputJSON))
 }
+
+func TestMaintenanceWindowWeekdays(t *testing.T) {
+	window := rule.MaintenanceWindow{
+		Weekdays: []string{"mon", "tue"},
+		Start:    "23:00",
+		End:      "02:00",
+		Timezone: "Europe/Berlin",
+	}
+
+	err := window.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	location, _ := time.LoadLocation("Europe/Berlin")
+
+	// Monday 2024-01-01
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 1, 23, 30, 0, 0, location)), true)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 2, 1, 59, 0, 0, location)), true)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 1, 22, 59, 0, 0, location)), false)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 1, 1, 0, 0, 0, location)), false)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 4, 1, 0, 0, 0, location)), false)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 3, 1, 0, 0, 0, location)), true)
+}
+
+func TestMaintenanceWindowCron(t *testing.T) {
+	window := rule.MaintenanceWindow{
+		Cron:     "30 2 * * 1-5",
+		Duration: "1h",
+		Mode:     rule.MaintenanceEvaluate,
+	}
+
+	err := window.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 1, 2, 30, 0, 0, time.UTC)), true)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 1, 3, 29, 0, 0, time.UTC)), true)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 1, 3, 30, 0, 0, time.UTC)), false)
+	assert.Equal(t, window.IsActive(time.Date(2024, 1, 6, 2, 45, 0, 0, time.UTC)), false)
+
+	// The day of month or the day of week (2024-02-01 is Thursday, 2024-01-08 is Monday)
+	monthly := rule.MaintenanceWindow{Cron: "0 2 1 * 1", Duration: "1h"}
+	if err = monthly.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, monthly.IsActive(time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC)), true)
+	assert.Equal(t, monthly.IsActive(time.Date(2024, 1, 8, 2, 30, 0, 0, time.UTC)), true)
+	assert.Equal(t, monthly.IsActive(time.Date(2024, 1, 9, 2, 30, 0, 0, time.UTC)), false)
+
+	// The long window started on the previous days
+	yearly := rule.MaintenanceWindow{Cron: "0 0 1 1 *", Duration: "2160h"}
+	if err = yearly.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, yearly.IsActive(time.Date(2024, 3, 15, 12, 0, 0, 0, time.UTC)), true)
+	assert.Equal(t, yearly.IsActive(time.Date(2024, 3, 30, 23, 59, 0, 0, time.UTC)), true)
+	assert.Equal(t, yearly.IsActive(time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)), false)
+	assert.Equal(t, yearly.IsActive(time.Date(2023, 12, 31, 23, 59, 0, 0, time.UTC)), false)
+
+	invalid := rule.MaintenanceWindow{Cron: "* * *", Duration: "1h"}
+	assert.NotEqual(t, invalid.Compile(), nil)
+}
+
+func TestDescriptionTemplate(t *testing.T) {
+	r := rule.Rule{
+		Name:        "Error rate",
+		Description: "Errors: {{ percent .Values.errors 1 }} of {{ .Response.total }}, latency {{ duration .Values.condition_2 }}, size {{ bytes .Values.size }}",
+		Rules:       []rule.RuleCondition{{Name: "errors"}, {}, {Name: "size"}},
+	}
+
+	err := r.CompileDescription()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.RulesResults = []interface{}{0.1234, 3725.0, 1536.0}
+	r.Response = map[string]interface{}{"total": 42}
+
+	assert.Equal(t, r.RenderDescription(), "Errors: 12.3% of 42, latency 1h 2m 5s, size 1.5 KiB")
+
+	// The positional placeholders are still supported
+	legacy := rule.Rule{Description: "Errors: {}, latency: {}"}
+	if err = legacy.CompileDescription(); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy.RulesResults = []interface{}{5}
+	assert.Equal(t, legacy.RenderDescription(), "Errors: 5, latency: 0")
+
+	// A missing key is an error, the description is shown as is
+	missing := rule.Rule{Description: "Errors: {{ .Values.unknown }}"}
+	if err = missing.CompileDescription(); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, missing.RenderDescription(), "Errors: {{ .Values.unknown }}")
+
+	invalid := rule.Rule{Description: "{{ .Values.errors"}
+	assert.NotEqual(t, invalid.CompileDescription(), nil)
+}
+
+func TestConditionExpression(t *testing.T) {
+	condition := rule.RuleCondition{Expr: "errors.doc_count / hits.total > 0.1 && hits.total > 5 && abs(min(delta, -3)) == 3"}
+
+	err := condition.Compile()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := map[string]interface{}{
+		"errors": map[string]interface{}{"doc_count": 2.0},
+		"hits":   map[string]interface{}{"total": 10.0},
+		"delta":  1.0,
+	}
+
+	isMet, err := condition.Evaluate(response)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, isMet, true)
+
+	response["hits"] = map[string]interface{}{"total": 40.0}
+	isMet, _ = condition.Evaluate(response)
+	assert.Equal(t, isMet, false)
+
+	invalid := rule.RuleCondition{Expr: "errors.doc_count >"}
+	assert.NotEqual(t, invalid.Compile(), nil)
+
+	notBoolean := rule.RuleCondition{Expr: "1 + 2"}
+	assert.NotEqual(t, notBoolean.Compile(), nil)
+
+	missing := rule.RuleCondition{Expr: "body.healthy"}
+	if err = missing.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	isMet, err = missing.Evaluate(map[string]interface{}{"body": map[string]interface{}{}})
+	assert.NotEqual(t, err, nil)
+	assert.Equal(t, isMet, false)
+}
+
+func TestConditionGroups(t *testing.T) {
+	// status != 200 OR (latency > 2 AND NOT body.healthy == true)
+	r := rule.Rule{
+		Name:  "Service health",
+		Match: rule.MatchAny,
+		Rules: []rule.RuleCondition{
+			{Status: 200},
+			{
+				Match: rule.MatchAll,
+				Rules: []rule.RuleCondition{
+					{Name: "latency", Field: "body.latency", Operator: "gt", Value: 2},
+					{Expr: "body.healthy == true", Not: true},
+				},
+			},
+		},
+	}
+
+	err := r.GetRule("rules/health.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response := func(status int, latency float64, healthy bool) map[string]interface{} {
+		return map[string]interface{}{
+			"status": status,
+			"body":   map[string]interface{}{"latency": latency, "healthy": healthy},
+		}
+	}
+
+	r.ProcessResponse(response(200, 1, false))
+	assert.Equal(t, r.IsFire, false)
+
+	r.ProcessResponse(response(200, 3, false))
+	assert.Equal(t, r.IsFire, true)
+	assert.Equal(t, r.Values()["latency"], 3.0)
+
+	r.ProcessResponse(response(200, 3, true))
+	assert.Equal(t, r.IsFire, false)
+
+	r.ProcessResponse(response(500, 1, true))
+	assert.Equal(t, r.IsFire, true)
+
+	invalid := rule.Rule{Match: "some"}
+	assert.NotEqual(t, invalid.GetRule("rules/invalid.json"), nil)
+}
+
+func TestConditionOperators(t *testing.T) {
+	response := map[string]interface{}{
+		"count":   "12",
+		"healthy": "true",
+		"region":  "eu-west-1",
+		"tags":    []interface{}{"api", "public"},
+		"empty":   nil,
+	}
+
+	cases := []struct {
+		condition rule.RuleCondition
+		isMet     bool
+	}{
+		{rule.RuleCondition{Field: "count", Operator: "gte", Value: 12}, true},
+		{rule.RuleCondition{Field: "count", Operator: "lte", Value: 11.5}, false},
+		{rule.RuleCondition{Field: "count", Operator: "between", Value: []interface{}{10, 20}}, true},
+		{rule.RuleCondition{Field: "count", Operator: "in", Value: []interface{}{1, 12.0}}, true},
+		{rule.RuleCondition{Field: "region", Operator: "not_in", Value: []interface{}{"eu-west-1"}}, false},
+		{rule.RuleCondition{Field: "healthy", Operator: "ne", Value: true}, true},
+		{rule.RuleCondition{Field: "healthy", Operator: "eq", Value: false}, true},
+		{rule.RuleCondition{Field: "region", Operator: "eq", Value: "eu-west-1"}, false},
+		{rule.RuleCondition{Field: "region", Operator: "ne", Value: "eu-west-1"}, true},
+		{rule.RuleCondition{Field: "region", Operator: "ne", Value: "us-east-1"}, false},
+		{rule.RuleCondition{Field: "region", Operator: "contains", Value: "west"}, true},
+		{rule.RuleCondition{Field: "tags", Operator: "contains", Value: "public"}, true},
+		{rule.RuleCondition{Field: "region", Operator: "regex", Value: "^us-"}, false},
+		{rule.RuleCondition{Field: "region", Operator: "exists"}, true},
+		{rule.RuleCondition{Field: "empty", Operator: "exists"}, false},
+		{rule.RuleCondition{Field: "missing", Operator: "not_exists"}, true},
+	}
+
+	for _, c := range cases {
+		err := c.condition.Compile()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		level, _, _ := c.condition.Check(response, rule.LevelOK)
+		assert.Equal(t, level == rule.LevelCritical, c.isMet)
+	}
+
+	invalid := []rule.RuleCondition{
+		{Field: "count", Operator: "greater", Value: 1},
+		{Field: "count", Operator: "gt", Value: "many"},
+		{Field: "count", Operator: "between", Value: []interface{}{1}},
+		{Field: "region", Operator: "regex", Value: "("},
+	}
+
+	for _, condition := range invalid {
+		assert.NotEqual(t, condition.Compile(), nil)
+	}
+}
+
+func TestSeverityThresholds(t *testing.T) {
+	r := rule.Rule{
+		Name: "Error rate",
+		Rules: []rule.RuleCondition{
+			{Field: "total", Operator: "gt", Value: 5},
+			{Field: "errors", Operator: "gt", Warning: 0.05, Critical: 0.1},
+		},
+	}
+
+	err := r.GetRule("rules/error-rate.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ProcessResponse(map[string]interface{}{"total": 10, "errors": 0.01})
+	assert.Equal(t, r.Status(), "ok")
+
+	r.ProcessResponse(map[string]interface{}{"total": 10, "errors": 0.07})
+	assert.Equal(t, r.Status(), "warning")
+	assert.Equal(t, r.NotificationEvent(time.Now()).Severity, "warning")
+
+	r.ProcessResponse(map[string]interface{}{"total": 10, "errors": 0.2})
+	assert.Equal(t, r.Status(), "critical")
+
+	// The "all" mode takes the lowest level of the conditions
+	r.ProcessResponse(map[string]interface{}{"total": 1, "errors": 0.2})
+	assert.Equal(t, r.Status(), "ok")
+	assert.Equal(t, r.NotificationEvent(time.Now()).Severity, "critical")
+}
+
+func TestPendingState(t *testing.T) {
+	var r rule.Rule
+	err := json.Unmarshal([]byte(`{
+		"name": "Latency",
+		"for": 3,
+		"resolve_after": "1h",
+		"rules": [{ "field": "latency", "operator": "gt", "value": 2 }]
+	}`), &r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err = r.GetRule("rules/latency.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	high := map[string]interface{}{"latency": 5}
+	low := map[string]interface{}{"latency": 1}
+
+	r.ProcessResponse(high)
+	r.ProcessResponse(high)
+	assert.Equal(t, r.Status(), "pending")
+
+	// The pending state survives the reload of the rule
+	registry := rule.Registry{Rules: map[string]*rule.Rule{}}
+	registry.AddRule(r)
+	reloaded := r
+	reloaded.PendingSince, reloaded.PendingEvaluations = nil, 0
+	registry.AddRule(reloaded)
+
+	registry.Rules[r.UUID].ProcessResponse(high)
+	assert.Equal(t, registry.Rules[r.UUID].Status(), "critical")
+
+	// The rule is resolved only after resolve_after
+	registry.Rules[r.UUID].ProcessResponse(low)
+	assert.Equal(t, registry.Rules[r.UUID].Status(), "critical")
+
+	var invalid rule.Rule
+	assert.NotEqual(t, json.Unmarshal([]byte(`{"fire_after": "soon"}`), &invalid), nil)
+}
+
+func TestHysteresis(t *testing.T) {
+	r := rule.Rule{
+		Name:  "Error rate",
+		Rules: []rule.RuleCondition{{Field: "errors", Operator: "gt", Value: 0.1, ResolveValue: 0.05}},
+	}
+
+	err := r.GetRule("rules/error-rate.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ProcessResponse(map[string]interface{}{"errors": 0.08})
+	assert.Equal(t, r.IsFire, false)
+
+	r.ProcessResponse(map[string]interface{}{"errors": 0.12})
+	assert.Equal(t, r.IsFire, true)
+
+	r.ProcessResponse(map[string]interface{}{"errors": 0.08})
+	assert.Equal(t, r.IsFire, true)
+
+	r.ProcessResponse(map[string]interface{}{"errors": 0.04})
+	assert.Equal(t, r.IsFire, false)
+
+	invalid := rule.RuleCondition{Field: "errors", Operator: "gt", Value: 0.1, ResolveValue: 0.2}
+	assert.NotEqual(t, invalid.Compile(), nil)
+
+	// The condition which didn't fire doesn't hold the rule
+	health := rule.Rule{
+		Name:  "Service health",
+		Match: rule.MatchAny,
+		Rules: []rule.RuleCondition{
+			{Field: "errors", Operator: "gt", Value: 0.1, ResolveValue: 0.05},
+			{Field: "latency", Operator: "gt", Value: 2},
+		},
+	}
+
+	if err = health.GetRule("rules/health.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	health.ProcessResponse(map[string]interface{}{"errors": 0.08, "latency": 3})
+	assert.Equal(t, health.IsFire, true)
+
+	health.ProcessResponse(map[string]interface{}{"errors": 0.08, "latency": 1})
+	assert.Equal(t, health.IsFire, false)
+
+	health.ProcessResponse(map[string]interface{}{"errors": 0.12, "latency": 1})
+	assert.Equal(t, health.IsFire, true)
+
+	health.ProcessResponse(map[string]interface{}{"errors": 0.08, "latency": 1})
+	assert.Equal(t, health.IsFire, true)
+
+	// The reload keeps the hysteresis of the firing condition
+	r.ProcessResponse(map[string]interface{}{"errors": 0.12})
+	assert.Equal(t, r.IsFire, true)
+
+	registry := rule.Registry{Rules: make(map[string]*rule.Rule)}
+	registry.AddRule(r)
+
+	reloaded := rule.Rule{
+		Name:  "Error rate",
+		Rules: []rule.RuleCondition{{Field: "errors", Operator: "gt", Value: 0.1, ResolveValue: 0.05}},
+	}
+
+	if err = reloaded.GetRule("rules/error-rate.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	registry.AddRule(reloaded)
+	current := registry.Rules[reloaded.UUID]
+	assert.Equal(t, current.IsFire, true)
+
+	current.ProcessResponse(map[string]interface{}{"errors": 0.08})
+	assert.Equal(t, current.IsFire, true)
+}
+
+func TestFlapDetection(t *testing.T) {
+	enabled := true
+	r := rule.Rule{
+		Name:          "Latency",
+		FlapDetection: &rule.FlapDetection{Enabled: &enabled},
+		Rules:         []rule.RuleCondition{{Field: "latency", Operator: "gt", Value: 2}},
+	}
+
+	err := r.GetRule("rules/latency.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	high := map[string]interface{}{"latency": 5}
+	low := map[string]interface{}{"latency": 1}
+
+	for i := 0; i < 3; i++ {
+		r.ProcessResponse(high)
+		r.ProcessResponse(low)
+	}
+
+	assert.Equal(t, r.IsFlapping, true)
+	assert.Equal(t, r.Status(), "flapping")
+
+	for i := 0; i < 21; i++ {
+		r.ProcessResponse(low)
+	}
+
+	assert.Equal(t, r.IsFlapping, false)
+	assert.Equal(t, r.Status(), "ok")
+
+	// The newer changes have the higher weight
+	assert.Equal(t, rule.FlapScore([]bool{true, false, false, false, false}, 5) < rule.FlapScore([]bool{false, false, false, false, true}, 5), true)
+}
+
+func TestNoDataState(t *testing.T) {
+	r := rule.Rule{
+		Name:        "Error rate",
+		NoDataState: rule.PolicyNoData,
+		ErrorState:  rule.PolicyAlerting,
+		Rules:       []rule.RuleCondition{{Field: "errors", Operator: "gt", Value: 0.1}},
+	}
+
+	err := r.GetRule("rules/error-rate.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r.ProcessResponse(map[string]interface{}{"total": 10})
+	assert.Equal(t, r.Status(), "no_data")
+	assert.Equal(t, r.IsFire, false)
+	assert.NotEqual(t, r.LastErrorAt, nil)
+
+	r.RecordError(errors.New("connection refused"))
+	assert.Equal(t, r.DataState, rule.DataStateError)
+	assert.Equal(t, r.Status(), "critical")
+	assert.Equal(t, *r.LastError, "connection refused")
+
+	r.ProcessResponse(map[string]interface{}{"errors": 0.01})
+	assert.Equal(t, r.IsStale(), false)
+	assert.Equal(t, r.Status(), "ok")
+
+	invalid := rule.Rule{NoDataState: "ignore"}
+	assert.NotEqual(t, invalid.GetRule("rules/invalid.json"), nil)
+
+	// The met condition fires the "any" rule despite the missing field of the other one
+	health := rule.Rule{
+		Name:        "Service health",
+		Match:       rule.MatchAny,
+		NoDataState: rule.PolicyNoData,
+		Rules:       []rule.RuleCondition{{Status: 200}, {Field: "latency", Operator: "gt", Value: 2}},
+	}
+
+	if err = health.GetRule("rules/health.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	health.ProcessResponse(map[string]interface{}{"status": 500})
+	assert.Equal(t, health.IsStale(), false)
+	assert.Equal(t, health.IsFire, true)
+
+	health.ProcessResponse(map[string]interface{}{"status": 200})
+	assert.Equal(t, health.DataState, rule.DataStateNoData)
+}
+
+func TestInhibition(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "inhibit.json")
+	inhibitRules := `[{"source": {"match": {"name": "Database down"}}, "target": {"match_re": {"name": "API .*"}}, "equal": ["scope"]}]`
+	if err := os.WriteFile(path, []byte(inhibitRules), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := rule.Registry{Rules: make(map[string]*rule.Rule)}
+	if err := registry.LoadInhibitRules(path); err != nil {
+		t.Fatal(err)
+	}
+
+	eu, us := "eu", "us"
+	for _, r := range []rule.Rule{
+		{Name: "API latency", Scope: &eu},
+		{Name: "API errors", Scope: &us},
+		{Name: "Database down", Scope: &eu},
+	} {
+		if err := r.GetRule("rules/inhibit.json"); err != nil {
+			t.Fatal(err)
+		}
+
+		registry.AddRule(r)
+	}
+
+	latency := registry.Rules[utils.GenerateRuleUUID("inhibit.json", "API latency")]
+	errorsRule := registry.Rules[utils.GenerateRuleUUID("inhibit.json", "API errors")]
+	database := registry.Rules[utils.GenerateRuleUUID("inhibit.json", "Database down")]
+
+	// The target is processed before the source in the same evaluation
+	latency.IsFire = true
+	errorsRule.IsFire = true
+	registry.CheckInhibition(latency)
+	assert.Equal(t, latency.InhibitedBy == nil, true)
+
+	database.IsFire = true
+	registry.CheckInhibitions()
+	assert.Equal(t, *latency.InhibitedBy, database.UUID)
+	assert.Equal(t, *registry.Inhibitor(latency.NotificationEvent(time.Now())), database.UUID)
+
+	// The scope differs from the source
+	assert.Equal(t, errorsRule.InhibitedBy == nil, true)
+
+	// The source doesn't inhibit itself and is not a target
+	assert.Equal(t, database.InhibitedBy == nil, true)
+
+	database.IsFire = false
+	registry.CheckInhibitions()
+	assert.Equal(t, latency.InhibitedBy == nil, true)
+
+	invalid := filepath.Join(t.TempDir(), "invalid.json")
+	if err := os.WriteFile(invalid, []byte(`[{"equal": ["host"]}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, registry.LoadInhibitRules(invalid), nil)
+}
+
+func TestAcknowledgement(t *testing.T) {
+	received := make(chan notifier.Event, 10)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notifier.Event
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Error(err)
+		}
+
+		received <- event
+	}))
+	defer server.Close()
+
+	next := func() notifier.Event {
+		select {
+		case event := <-received:
+			return event
+		case <-time.After(time.Second):
+			t.Fatal("notification is not received")
+			return notifier.Event{}
+		}
+	}
+
+	dir := t.TempDir()
+	config := fmt.Sprintf(`{
+		"receivers": [{"name": "webhook", "webhook": {"url": %q}}],
+		"escalation_policies": [{"name": "critical", "tiers": [{"receivers": ["webhook"]}, {"delay": "200ms", "receivers": ["webhook"]}]}]
+	}`, server.URL)
+
+	if err := os.WriteFile(filepath.Join(dir, "notifiers.json"), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the escalation policy sends to the webhook, the notifications of the other tests have no receivers
+	if err := os.WriteFile(filepath.Join(dir, "routes.json"), []byte(`{"receivers": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	options := notifier.SetupOptions{ConfigFile: filepath.Join(dir, "notifiers.json"), RoutesFile: filepath.Join(dir, "routes.json")}
+	if err := notifier.Setup(options); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := rule.Registry{Rules: make(map[string]*rule.Rule)}
+	r := rule.Rule{
+		Name:       "Error rate",
+		Escalation: "critical",
+		Rules:      []rule.RuleCondition{{Field: "errors", Operator: "gt", Value: 0.1}},
+	}
+
+	if err := r.GetRule("rules/ack.json"); err != nil {
+		t.Fatal(err)
+	}
+
+	registry.AddRule(r)
+	firing := registry.Rules[r.UUID]
+
+	gin.SetMode(gin.TestMode)
+	router := setupRouter(&registry)
+	request := func(method string, path string, body string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:12345"
+		req.Header.Set("Content-Type", "application/json")
+
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+
+		return recorder.Code
+	}
+
+	ackPath := fmt.Sprintf("/api/rules/%s/ack", r.UUID)
+
+	// Only the firing rule can be acknowledged
+	assert.Equal(t, request("POST", ackPath, `{"by": "ops"}`), http.StatusBadRequest)
+	assert.Equal(t, request("POST", "/api/rules/unknown/ack", `{"by": "ops"}`), http.StatusNotFound)
+
+	firing.ProcessResponse(map[string]interface{}{"errors": 0.5})
+	assert.Equal(t, next().Status, notifier.StatusFiring)
+	assert.Equal(t, notifier.Escalation(r.UUID).Active, true)
+
+	assert.Equal(t, request("POST", ackPath, `{"by": "ops", "expires_in": "soon"}`), http.StatusBadRequest)
+	assert.Equal(t, request("POST", ackPath, `{"by": "ops", "comment": "on it", "expires_in": "1h"}`), http.StatusOK)
+	assert.Equal(t, firing.Ack.By, "ops")
+	assert.Equal(t, firing.Ack.IsActive(), true)
+
+	// The escalation is stopped while the rule is acknowledged
+	assert.Equal(t, notifier.Escalation(r.UUID).Active, false)
+	firing.ProcessResponse(map[string]interface{}{"errors": 0.5})
+
+	// The acknowledgement is cleared when the rule is resolved
+	firing.ProcessResponse(map[string]interface{}{"errors": 0.01})
+	assert.Equal(t, firing.Ack == nil, true)
+	assert.Equal(t, next().Status, notifier.StatusResolved)
+
+	assert.Equal(t, request("DELETE", ackPath, ""), http.StatusOK)
+}
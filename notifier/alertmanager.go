@@ -0,0 +1,236 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wavix/w-alerts/types"
+	"github.com/wavix/w-alerts/utils"
+)
+
+type AlertmanagerConfig struct {
+	Url            string            `json:"url"`
+	Headers        map[string]string `json:"headers"`
+	Labels         map[string]string `json:"labels"`
+	GeneratorUrl   *string           `json:"generator_url"`
+	ResendInterval string            `json:"resend_interval"`
+}
+
+// Alertmanager pushes alerts to the Alertmanager API (/api/v2/alerts) on every
+// evaluation of a firing rule. Active alerts are resent periodically, so
+// Alertmanager doesn't resolve them by timeout. The acknowledged, silenced and
+// inhibited alerts are resent too, their state is kept in the annotations
+type Alertmanager struct {
+	name           string
+	config         AlertmanagerConfig
+	resendInterval time.Duration
+
+	active map[string]*alertmanagerState
+	stop   chan struct{}
+	mutex  sync.Mutex
+}
+
+type alertmanagerState struct {
+	event    Event
+	pushedAt time.Time
+}
+
+type AlertmanagerAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     *time.Time        `json:"startsAt,omitempty"`
+	EndsAt       *time.Time        `json:"endsAt,omitempty"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+}
+
+func NewAlertmanager(name string, config AlertmanagerConfig) (*Alertmanager, error) {
+	if config.Url == "" {
+		return nil, errors.New("alertmanager url is required")
+	}
+
+	alertmanager := &Alertmanager{
+		name:           name,
+		config:         config,
+		resendInterval: time.Minute,
+		active:         make(map[string]*alertmanagerState),
+		stop:           make(chan struct{}),
+	}
+
+	if config.ResendInterval != "" {
+		interval, err := time.ParseDuration(config.ResendInterval)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing alertmanager resend interval: %w", err)
+		}
+
+		alertmanager.resendInterval = interval
+	}
+
+	go alertmanager.resend()
+
+	return alertmanager, nil
+}
+
+func (alertmanager *Alertmanager) Name() string {
+	return alertmanager.name
+}
+
+// Close stops resending the active alerts
+func (alertmanager *Alertmanager) Close() {
+	select {
+	case <-alertmanager.stop:
+	default:
+		close(alertmanager.stop)
+	}
+}
+
+// Acknowledge updates the acknowledgement of the active alert
+func (alertmanager *Alertmanager) Acknowledge(uuid string, ack *types.Acknowledgement) {
+	alertmanager.mutex.Lock()
+	defer alertmanager.mutex.Unlock()
+
+	if state, exists := alertmanager.active[uuid]; exists {
+		state.event.Ack = ack
+	}
+}
+
+func (alertmanager *Alertmanager) Notify(event Event) error {
+	return alertmanager.NotifyGroup([]Event{event})
+}
+
+func (alertmanager *Alertmanager) NotifyGroup(events []Event) error {
+	alertmanager.mutex.Lock()
+	for _, event := range events {
+		if event.Flapping {
+			// The flapping notification is pushed once, the active state is kept by the transitions
+			continue
+		}
+
+		if event.IsFiring() {
+			alertmanager.active[event.UUID] = &alertmanagerState{event: event, pushedAt: time.Now()}
+		} else {
+			delete(alertmanager.active, event.UUID)
+		}
+	}
+	alertmanager.mutex.Unlock()
+
+	return alertmanager.push(events)
+}
+
+// NotifyEvaluation pushes the firing rule again with the latest values
+func (alertmanager *Alertmanager) NotifyEvaluation(event Event) error {
+	if !event.IsFiring() {
+		return nil
+	}
+
+	return alertmanager.Notify(event)
+}
+
+func (alertmanager *Alertmanager) Alert(event Event) AlertmanagerAlert {
+	labels := map[string]string{
+		"alertname": event.Name,
+		"uuid":      event.UUID,
+	}
+
+	if event.Scope != nil && *event.Scope != "" {
+		labels["scope"] = *event.Scope
+	}
+
+	if event.File != "" {
+		labels["file"] = event.File
+	}
+
+	for key, value := range alertmanager.config.Labels {
+		labels[key] = value
+	}
+
+	annotations := map[string]string{
+		"summary":     event.Title(),
+		"description": event.Description,
+	}
+
+	// The state is not a label, changing a label would start a new alert in Alertmanager
+	if event.IsFiring() {
+		if event.Ack.IsActive() {
+			annotations["acknowledged_by"] = event.Ack.By
+		}
+
+		if silence := Silenced(event); silence != nil {
+			annotations["silenced_by"] = silence.ID
+		}
+
+		if inhibitedBy := dispatcher.inhibitedBy(event); inhibitedBy != nil {
+			annotations["inhibited_by"] = *inhibitedBy
+		}
+	}
+
+	alert := AlertmanagerAlert{
+		Labels:      labels,
+		Annotations: annotations,
+		StartsAt:    event.FiredAt,
+		EndsAt:      event.ResolvedAt,
+	}
+
+	if alertmanager.config.GeneratorUrl != nil {
+		alert.GeneratorURL = *alertmanager.config.GeneratorUrl
+	}
+
+	return alert
+}
+
+func (alertmanager *Alertmanager) push(events []Event) error {
+	alerts := make([]AlertmanagerAlert, 0, len(events))
+	for _, event := range events {
+		alerts = append(alerts, alertmanager.Alert(event))
+	}
+
+	payload, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	url := strings.TrimSuffix(alertmanager.config.Url, "/")
+	if !strings.HasSuffix(url, "/api/v2/alerts") {
+		url += "/api/v2/alerts"
+	}
+
+	return postJSON("POST", url, alertmanager.config.Headers, payload)
+}
+
+func (alertmanager *Alertmanager) resend() {
+	ticker := time.NewTicker(alertmanager.resendInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-alertmanager.stop:
+			return
+		case <-ticker.C:
+		}
+
+		events := make([]Event, 0)
+
+		alertmanager.mutex.Lock()
+		for _, state := range alertmanager.active {
+			if time.Since(state.pushedAt) < alertmanager.resendInterval {
+				continue
+			}
+
+			state.pushedAt = time.Now()
+			events = append(events, state.event)
+		}
+		alertmanager.mutex.Unlock()
+
+		if len(events) == 0 {
+			continue
+		}
+
+		err := alertmanager.push(events)
+		if err != nil {
+			utils.Logger.Context(alertmanager.name).Error().Msgf("Error resending active alerts: %v", err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+	"github.com/wavix/w-alerts/types"
+)
+
+func TestAlertmanagerPushResendAndResolve(t *testing.T) {
+	var mutex sync.Mutex
+	pushes := make([][]AlertmanagerAlert, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/api/v2/alerts")
+
+		body, _ := io.ReadAll(r.Body)
+
+		var alerts []AlertmanagerAlert
+		if err := json.Unmarshal(body, &alerts); err != nil {
+			t.Error(err)
+		}
+
+		mutex.Lock()
+		pushes = append(pushes, alerts)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	received := func() [][]AlertmanagerAlert {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		return pushes
+	}
+
+	if err := LoadSilences(filepath.Join(t.TempDir(), "silences.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	alertmanager, err := NewAlertmanager("alertmanager", AlertmanagerConfig{
+		Url:            server.URL,
+		Labels:         map[string]string{"team": "ops"},
+		ResendInterval: "50ms",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alertmanager.Close()
+
+	scope := "api"
+	firedAt := time.Now().UTC()
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "Error rate", Scope: &scope, FiredAt: &firedAt}
+
+	assert.Equal(t, alertmanager.Notify(firing), nil)
+	assert.Equal(t, len(received()), 1)
+	assert.Equal(t, received()[0][0].Labels["alertname"], "Error rate")
+	assert.Equal(t, received()[0][0].Labels["scope"], "api")
+	assert.Equal(t, received()[0][0].Labels["team"], "ops")
+	assert.Equal(t, received()[0][0].EndsAt == nil, true)
+
+	// The active alert is resent
+	time.Sleep(130 * time.Millisecond)
+	assert.Equal(t, len(received()) > 1, true)
+
+	// The acknowledged alert is resent with the annotation
+	alertmanager.Acknowledge("a", &types.Acknowledgement{By: "ops"})
+	count := len(received())
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(t, len(received()) > count, true)
+	assert.Equal(t, received()[len(received())-1][0].Annotations["acknowledged_by"], "ops")
+
+	// The silenced alert is resent with the annotation
+	alertmanager.Acknowledge("a", nil)
+	silence, err := AddSilence(Silence{Matchers: Matchers{Match: map[string]string{"uuid": "a"}}, EndsAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	count = len(received())
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(t, len(received()) > count, true)
+
+	last := received()[len(received())-1][0]
+	assert.Equal(t, last.Annotations["silenced_by"], silence.ID)
+	assert.Equal(t, last.Annotations["acknowledged_by"], "")
+	ExpireSilence(silence.ID)
+
+	resolvedAt := time.Now().UTC()
+	resolved := firing
+	resolved.Status = StatusResolved
+	resolved.ResolvedAt = &resolvedAt
+
+	assert.Equal(t, alertmanager.Notify(resolved), nil)
+	count = len(received())
+	assert.Equal(t, received()[count-1][0].EndsAt != nil, true)
+
+	// The resolved alert is not resent
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(t, len(received()), count)
+
+	// The flapping notification is pushed once
+	flapping := firing
+	flapping.Flapping = true
+	assert.Equal(t, alertmanager.Notify(flapping), nil)
+	time.Sleep(120 * time.Millisecond)
+	assert.Equal(t, len(received()), count+1)
+}
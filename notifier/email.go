@@ -0,0 +1,400 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	EmailTLSNone     = "none"
+	EmailTLSStartTLS = "starttls"
+	EmailTLSImplicit = "tls"
+)
+
+const defaultEmailText = `{{ .Title }} is {{ .Status }}
+
+{{ .Description }}
+
+Values:
+{{- range $index, $value := .RulesResults }}
+  condition_{{ inc $index }}: {{ $value }}
+{{- end }}
+
+Rule file: {{ if .File }}{{ .File }}{{ else }}static alert{{ end }}
+Fired at: {{ time .FiredAt }}
+Resolved at: {{ time .ResolvedAt }}
+`
+
+const defaultEmailHTML = `<html>
+<body style="font-family: sans-serif">
+<h2 style="color: {{ if .IsFiring }}#dc3545{{ else }}#28a745{{ end }}">{{ .Title }} is {{ .Status }}</h2>
+<p>{{ .Description }}</p>
+<table cellpadding="4">
+{{- range $index, $value := .RulesResults }}
+<tr><td>condition_{{ inc $index }}</td><td>{{ $value }}</td></tr>
+{{- end }}
+</table>
+<p>
+Rule file: {{ if .File }}{{ .File }}{{ else }}static alert{{ end }}<br>
+Fired at: {{ time .FiredAt }}<br>
+Resolved at: {{ time .ResolvedAt }}
+</p>
+</body>
+</html>
+`
+
+type EmailConfig struct {
+	Host         string              `json:"host"`
+	Port         int                 `json:"port"`
+	Username     *string             `json:"username"`
+	Password     *string             `json:"password"`
+	From         string              `json:"from"`
+	TLS          string              `json:"tls"`
+	To           []string            `json:"to"`
+	Scopes       map[string][]string `json:"scopes"`
+	Rules        map[string][]string `json:"rules"`
+	Subject      *string             `json:"subject"`
+	TextTemplate *string             `json:"text_template"`
+	HTMLTemplate *string             `json:"html_template"`
+}
+
+type Email struct {
+	name    string
+	config  EmailConfig
+	subject *template.Template
+	text    *template.Template
+	html    *htmltemplate.Template
+}
+
+var emailFuncs = map[string]interface{}{
+	"inc": func(index int) int {
+		return index + 1
+	},
+	"time": func(value *time.Time) string {
+		if value == nil {
+			return "-"
+		}
+
+		return value.UTC().Format(time.RFC1123)
+	},
+}
+
+func NewEmail(name string, config EmailConfig) (*Email, error) {
+	if config.Host == "" || config.Port == 0 {
+		return nil, errors.New("email host and port are required")
+	}
+
+	if config.From == "" {
+		return nil, errors.New("email sender is required")
+	}
+
+	if config.TLS == "" {
+		config.TLS = EmailTLSStartTLS
+	}
+
+	if !slices.Contains([]string{EmailTLSNone, EmailTLSStartTLS, EmailTLSImplicit}, config.TLS) {
+		return nil, fmt.Errorf("unsupported email tls mode '%s'", config.TLS)
+	}
+
+	subject := "[{{ .Status | upper }}] {{ .Title }}"
+	if config.Subject != nil {
+		subject = *config.Subject
+	}
+
+	text := defaultEmailText
+	if config.TextTemplate != nil {
+		text = *config.TextTemplate
+	}
+
+	html := defaultEmailHTML
+	if config.HTMLTemplate != nil {
+		html = *config.HTMLTemplate
+	}
+
+	email := &Email{name: name, config: config}
+	textFuncs := template.FuncMap{"upper": strings.ToUpper}
+	for key, fn := range emailFuncs {
+		textFuncs[key] = fn
+	}
+
+	var err error
+	if email.subject, err = template.New("subject").Funcs(textFuncs).Parse(subject); err != nil {
+		return nil, fmt.Errorf("error parsing email subject: %w", err)
+	}
+
+	if email.text, err = template.New("text").Funcs(textFuncs).Parse(text); err != nil {
+		return nil, fmt.Errorf("error parsing email text template: %w", err)
+	}
+
+	if email.html, err = htmltemplate.New("html").Funcs(htmltemplate.FuncMap(emailFuncs)).Parse(html); err != nil {
+		return nil, fmt.Errorf("error parsing email html template: %w", err)
+	}
+
+	return email, nil
+}
+
+func (email *Email) Name() string {
+	return email.name
+}
+
+// Recipients returns the common recipients together with the ones configured for the rule scope and the rule itself
+func (email *Email) Recipients(event Event) []string {
+	recipients := make([]string, 0)
+	add := func(addresses []string) {
+		for _, address := range ResolveRecipients(addresses, ContactEmail) {
+			if !slices.Contains(recipients, address) {
+				recipients = append(recipients, address)
+			}
+		}
+	}
+
+	add(email.config.To)
+
+	if event.Scope != nil {
+		add(email.config.Scopes[*event.Scope])
+	}
+
+	add(email.config.Rules[event.Name])
+	add(email.config.Rules[event.UUID])
+
+	return recipients
+}
+
+func (email *Email) Notify(event Event) error {
+	recipients := email.Recipients(event)
+	if len(recipients) == 0 {
+		return nil
+	}
+
+	message, err := email.Message(event, recipients)
+	if err != nil {
+		return err
+	}
+
+	return email.send(recipients, message)
+}
+
+// NotifyGroup sends a digest to every group of recipients
+func (email *Email) NotifyGroup(events []Event) error {
+	groups := make(map[string][]Event)
+	recipients := make(map[string][]string)
+	keys := make([]string, 0)
+
+	for _, event := range events {
+		eventRecipients := email.Recipients(event)
+		if len(eventRecipients) == 0 {
+			continue
+		}
+
+		key := strings.Join(eventRecipients, ",")
+		if _, exists := groups[key]; !exists {
+			keys = append(keys, key)
+			recipients[key] = eventRecipients
+		}
+
+		groups[key] = append(groups[key], event)
+	}
+
+	errs := make([]error, 0)
+	for _, key := range keys {
+		message, err := email.Digest(groups[key], recipients[key])
+		if err == nil {
+			err = email.send(recipients[key], message)
+		}
+
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Message builds a multipart/alternative message with the plain-text and HTML versions
+func (email *Email) Message(event Event, recipients []string) ([]byte, error) {
+	subject, text, html, err := email.render(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return email.build(subject, text, html, recipients, event.Timestamp)
+}
+
+// Digest builds a single message with the content of every event
+func (email *Email) Digest(events []Event, recipients []string) ([]byte, error) {
+	texts := make([]string, 0, len(events))
+	htmls := make([]string, 0, len(events))
+
+	for _, event := range events {
+		_, text, html, err := email.render(event)
+		if err != nil {
+			return nil, err
+		}
+
+		texts = append(texts, text)
+		htmls = append(htmls, html)
+	}
+
+	subject := fmt.Sprintf("[ALERTS] %s", DigestSummary(events))
+	text := strings.Join(texts, "\n----------------------------------------\n\n")
+	html := strings.Join(htmls, "\n<hr>\n")
+
+	return email.build(subject, text, html, recipients, events[len(events)-1].Timestamp)
+}
+
+func (email *Email) render(event Event) (string, string, string, error) {
+	var subject, text, html bytes.Buffer
+
+	if err := email.subject.Execute(&subject, event); err != nil {
+		return "", "", "", fmt.Errorf("error rendering email subject: %w", err)
+	}
+
+	if err := email.text.Execute(&text, event); err != nil {
+		return "", "", "", fmt.Errorf("error rendering email text: %w", err)
+	}
+
+	if err := email.html.Execute(&html, event); err != nil {
+		return "", "", "", fmt.Errorf("error rendering email html: %w", err)
+	}
+
+	return subject.String(), text.String(), html.String(), nil
+}
+
+func (email *Email) build(subject string, text string, html string, recipients []string, date time.Time) ([]byte, error) {
+	for _, recipient := range recipients {
+		if strings.ContainsAny(recipient, "\r\n") {
+			return nil, fmt.Errorf("invalid recipient %q", recipient)
+		}
+	}
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	for _, part := range []struct {
+		contentType string
+		content     string
+	}{
+		{"text/plain; charset=UTF-8", text},
+		{"text/html; charset=UTF-8", html},
+	} {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		encoder := quotedprintable.NewWriter(partWriter)
+		if _, err = encoder.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+
+		if err = encoder.Close(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", headerValue(email.config.From))
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(recipients, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", headerValue(subject)))
+	fmt.Fprintf(&message, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", writer.Boundary())
+	message.Write(body.Bytes())
+
+	return message.Bytes(), nil
+}
+
+// headerValue replaces the line breaks, so a value can't inject more headers
+func headerValue(value string) string {
+	return strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ").Replace(value)
+}
+
+func (email *Email) send(recipients []string, message []byte) error {
+	address := net.JoinHostPort(email.config.Host, fmt.Sprintf("%d", email.config.Port))
+	tlsConfig := &tls.Config{ServerName: email.config.Host}
+
+	var client *smtp.Client
+	if email.config.TLS == EmailTLSImplicit {
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}, "tcp", address, tlsConfig)
+		if err != nil {
+			return err
+		}
+
+		client, err = smtp.NewClient(conn, email.config.Host)
+		if err != nil {
+			conn.Close() // nolint:errcheck
+			return err
+		}
+	} else {
+		conn, err := net.DialTimeout("tcp", address, 10*time.Second)
+		if err != nil {
+			return err
+		}
+
+		client, err = smtp.NewClient(conn, email.config.Host)
+		if err != nil {
+			conn.Close() // nolint:errcheck
+			return err
+		}
+	}
+
+	defer client.Close() // nolint:errcheck
+
+	if email.config.TLS == EmailTLSStartTLS {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if email.config.Username != nil && email.config.Password != nil {
+		auth := smtp.PlainAuth("", *email.config.Username, *email.config.Password, email.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(email.config.From); err != nil {
+		return err
+	}
+
+	for _, recipient := range recipients {
+		if err := client.Rcpt(recipient); err != nil {
+			return err
+		}
+	}
+
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+
+	if err = writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
@@ -0,0 +1,144 @@
+package notifier
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+)
+
+type smtpSink struct {
+	listener   net.Listener
+	recipients []string
+	data       chan string
+}
+
+// newSMTPSink starts a minimal SMTP server which accepts a single message
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink := &smtpSink{listener: listener, data: make(chan string, 1)}
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close() // nolint:errcheck
+
+		reader := bufio.NewReader(conn)
+		write := func(line string) {
+			_, _ = conn.Write([]byte(line + "\r\n"))
+		}
+
+		write("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				write("250 localhost")
+			case strings.HasPrefix(command, "RCPT TO:"):
+				sink.recipients = append(sink.recipients, strings.Trim(strings.TrimSpace(line)[8:], "<>"))
+				write("250 OK")
+			case strings.HasPrefix(command, "DATA"):
+				write("354 End data with <CR><LF>.<CR><LF>")
+
+				var data strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					data.WriteString(dataLine)
+				}
+
+				sink.data <- data.String()
+				write("250 OK")
+			case strings.HasPrefix(command, "QUIT"):
+				write("221 Bye")
+				return
+			default:
+				write("250 OK")
+			}
+		}
+	}()
+
+	return sink
+}
+
+func TestEmailNotify(t *testing.T) {
+	sink := newSMTPSink(t)
+	defer sink.listener.Close() // nolint:errcheck
+
+	host, port, _ := net.SplitHostPort(sink.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	email, err := NewEmail("email", EmailConfig{
+		Host:   host,
+		Port:   portNumber,
+		From:   "alerts@example.com",
+		TLS:    EmailTLSNone,
+		To:     []string{"ops@example.com"},
+		Scopes: map[string][]string{"api": {"api@example.com", "ops@example.com"}},
+		Rules:  map[string][]string{"Error rate": {"owner@example.com"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := "api"
+	firedAt := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	err = email.Notify(Event{
+		Status:       StatusFiring,
+		Name:         "Error rate",
+		Scope:        &scope,
+		Description:  "Error rate is too high",
+		RulesResults: []interface{}{10, 0.5},
+		File:         "rules/es.json",
+		FiredAt:      &firedAt,
+		Timestamp:    firedAt,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := <-sink.data
+
+	assert.Equal(t, sink.recipients, []string{"ops@example.com", "api@example.com", "owner@example.com"})
+	assert.Equal(t, strings.Contains(data, "Subject: [FIRING] [API] Error rate"), true)
+	assert.Equal(t, strings.Contains(data, "Content-Type: text/html"), true)
+	assert.Equal(t, strings.Contains(data, "condition_2: 0.5"), true)
+	assert.Equal(t, strings.Contains(data, "Rule file: rules/es.json"), true)
+	assert.Equal(t, strings.Contains(data, "Fired at: Mon, 01 Jan 2024 10:00:00 UTC"), true)
+}
+
+func TestEmailHeaders(t *testing.T) {
+	email, err := NewEmail("email", EmailConfig{Host: "localhost", Port: 25, From: "alerts@example.com", TLS: EmailTLSNone, To: []string{"ops@example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := email.build("Ошибка\r\nBcc: victim@example.com", "", "", []string{"ops@example.com"}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	headers := strings.Split(string(message), "\r\n")
+	assert.Equal(t, headers[2], "Subject: =?utf-8?q?=D0=9E=D1=88=D0=B8=D0=B1=D0=BA=D0=B0_Bcc:_victim@example.com?=")
+	assert.Equal(t, strings.Contains(string(message), "\r\nBcc:"), false)
+
+	_, err = email.build("Error rate", "", "", []string{"ops@example.com\r\nBcc: victim@example.com"}, time.Now())
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,258 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/wavix/w-alerts/utils"
+)
+
+type EscalationTierConfig struct {
+	Delay     string   `json:"delay"`
+	Receivers []string `json:"receivers"`
+}
+
+type EscalationPolicyConfig struct {
+	Name           string                 `json:"name"`
+	Tiers          []EscalationTierConfig `json:"tiers"`
+	Repeat         int                    `json:"repeat"`
+	RepeatInterval string                 `json:"repeat_interval"`
+}
+
+// EscalationPolicy notifies the receivers of the first tier when the rule fires
+// and the next tiers after their delays while the rule is not acknowledged.
+// After the last tier the policy starts over from the first tier "repeat" times
+type EscalationPolicy struct {
+	Name           string
+	Tiers          []EscalationTier
+	Repeat         int
+	RepeatInterval time.Duration
+}
+
+type EscalationTier struct {
+	Delay     time.Duration
+	Receivers []string
+}
+
+type escalationState struct {
+	policy  *EscalationPolicy
+	event   Event // The latest firing event, sent to the next tiers
+	tier    int
+	reached int
+	stop    chan struct{}
+}
+
+// EscalationStatus is the current state of the escalation of a rule
+type EscalationStatus struct {
+	Policy string `json:"policy"`
+	Tier   int    `json:"tier"`
+	Active bool   `json:"active"`
+}
+
+func (config EscalationPolicyConfig) Build(receivers []string) (*EscalationPolicy, error) {
+	if config.Name == "" {
+		return nil, errors.New("escalation policy name is required")
+	}
+
+	if len(config.Tiers) == 0 {
+		return nil, fmt.Errorf("escalation policy '%s' has no tiers", config.Name)
+	}
+
+	policy := &EscalationPolicy{Name: config.Name, Repeat: config.Repeat, RepeatInterval: 10 * time.Minute}
+
+	if config.RepeatInterval != "" {
+		interval, err := time.ParseDuration(config.RepeatInterval)
+		if err != nil {
+			return nil, fmt.Errorf("escalation policy '%s': invalid repeat interval: %w", config.Name, err)
+		}
+
+		policy.RepeatInterval = interval
+	}
+
+	var previous time.Duration
+	for index, tierConfig := range config.Tiers {
+		tier := EscalationTier{Receivers: tierConfig.Receivers}
+
+		if tierConfig.Delay != "" {
+			delay, err := time.ParseDuration(tierConfig.Delay)
+			if err != nil {
+				return nil, fmt.Errorf("escalation policy '%s': invalid delay of tier %d: %w", config.Name, index+1, err)
+			}
+
+			tier.Delay = delay
+		}
+
+		if index > 0 && tier.Delay <= previous {
+			return nil, fmt.Errorf("escalation policy '%s': delay of tier %d must be greater than the previous one", config.Name, index+1)
+		}
+		previous = tier.Delay
+
+		for _, receiver := range tier.Receivers {
+			if !slices.Contains(receivers, receiver) {
+				return nil, fmt.Errorf("escalation policy '%s': unknown receiver '%s'", config.Name, receiver)
+			}
+		}
+
+		policy.Tiers = append(policy.Tiers, tier)
+	}
+
+	return policy, nil
+}
+
+// receivers returns the receivers of the tier, or of all tiers up to the tier
+func (policy *EscalationPolicy) receivers(tier int, upTo bool) []string {
+	names := make([]string, 0)
+
+	for index, escalationTier := range policy.Tiers {
+		if index > tier || (!upTo && index != tier) {
+			continue
+		}
+
+		for _, receiver := range escalationTier.Receivers {
+			if !slices.Contains(names, receiver) {
+				names = append(names, receiver)
+			}
+		}
+	}
+
+	return names
+}
+
+func (dispatcher *Dispatcher) policy(event Event) *EscalationPolicy {
+	if event.Escalation == "" {
+		return nil
+	}
+
+	dispatcher.Mutex.RLock()
+	defer dispatcher.Mutex.RUnlock()
+
+	policy, exists := dispatcher.Policies[event.Escalation]
+	if !exists {
+		utils.Logger.Context(event.Name).Warn().Msgf("Unknown escalation policy '%s'", event.Escalation)
+		return nil
+	}
+
+	return policy
+}
+
+// escalate starts the escalation of the firing rule or stops it when the rule
+// is resolved. The resolved event is sent to all the tiers notified so far.
+// The severity change doesn't restart the escalation, it's sent to the current tier
+func (dispatcher *Dispatcher) escalate(event Event) Event {
+	policy := dispatcher.policy(event)
+	if policy == nil {
+		return event
+	}
+
+	dispatcher.stateMutex.Lock()
+	defer dispatcher.stateMutex.Unlock()
+
+	state, exists := dispatcher.escalations[event.UUID]
+
+	if !event.IsFiring() {
+		if exists {
+			event.EscalationTier = state.reached
+			state.close()
+			delete(dispatcher.escalations, event.UUID)
+		}
+
+		return event
+	}
+
+	if exists && event.PreviousSeverity != "" {
+		state.event = event
+		event.EscalationTier = state.tier
+		return event
+	}
+
+	if exists {
+		state.close()
+	}
+
+	state = &escalationState{policy: policy, event: event, stop: make(chan struct{})}
+	dispatcher.escalations[event.UUID] = state
+	event.EscalationTier = 0
+
+	if !event.Ack.IsActive() {
+		go dispatcher.runEscalation(state)
+	}
+
+	return event
+}
+
+func (dispatcher *Dispatcher) runEscalation(state *escalationState) {
+	policy := state.policy
+	last := policy.Tiers[len(policy.Tiers)-1].Delay
+	cycle := last + policy.RepeatInterval
+	started := time.Now()
+
+	for loop := 0; loop <= policy.Repeat; loop++ {
+		for index, tier := range policy.Tiers {
+			if loop == 0 && index == 0 {
+				continue
+			}
+
+			at := started.Add(time.Duration(loop)*cycle + tier.Delay)
+			if !wait(time.Until(at), state.stop) {
+				return
+			}
+
+			dispatcher.stateMutex.Lock()
+			state.tier = index
+			state.reached = max(state.reached, index)
+			escalated := state.event
+			dispatcher.stateMutex.Unlock()
+
+			escalated.PreviousSeverity = ""
+			escalated.EscalationTier = index
+			escalated.Repeat = loop > 0 || index > 0
+			escalated.Timestamp = time.Now().UTC()
+
+			utils.Logger.Context(escalated.Name).Warn().Msgf("Escalated to tier %d of '%s'", index+1, policy.Name)
+			dispatcher.track(escalated, true)
+			dispatcher.enqueue(escalated)
+		}
+	}
+}
+
+// stopEscalation stops the escalation of the acknowledged rule, the tier is kept for the resolve
+func (dispatcher *Dispatcher) stopEscalation(uuid string) {
+	dispatcher.stateMutex.Lock()
+	defer dispatcher.stateMutex.Unlock()
+
+	if state, exists := dispatcher.escalations[uuid]; exists {
+		state.close()
+	}
+}
+
+func (state *escalationState) close() {
+	select {
+	case <-state.stop:
+	default:
+		close(state.stop)
+	}
+}
+
+func (state *escalationState) stopped() bool {
+	select {
+	case <-state.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Escalation returns the escalation state of the rule
+func Escalation(uuid string) *EscalationStatus {
+	dispatcher.stateMutex.Lock()
+	defer dispatcher.stateMutex.Unlock()
+
+	state, exists := dispatcher.escalations[uuid]
+	if !exists {
+		return nil
+	}
+
+	return &EscalationStatus{Policy: state.policy.Name, Tier: state.tier + 1, Active: !state.stopped()}
+}
@@ -0,0 +1,154 @@
+package notifier
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+	"github.com/wavix/w-alerts/types"
+)
+
+type namedRecorder struct {
+	recorder
+	name string
+}
+
+func (receiver *namedRecorder) Name() string {
+	return receiver.name
+}
+
+func TestEscalationPolicy(t *testing.T) {
+	first := &namedRecorder{name: "first"}
+	second := &namedRecorder{name: "second"}
+
+	policy, err := EscalationPolicyConfig{
+		Name: "critical",
+		Tiers: []EscalationTierConfig{
+			{Receivers: []string{"first"}},
+			{Delay: "50ms", Receivers: []string{"second"}},
+		},
+	}.Build([]string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := &Dispatcher{
+		Receivers:   []Notifier{first, second},
+		Policies:    map[string]*EscalationPolicy{"critical": policy},
+		firing:      make(map[string]*firingState),
+		escalations: make(map[string]*escalationState),
+	}
+
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "a", Escalation: "critical"}
+	dispatcher.Dispatch(dispatcher.escalate(firing))
+
+	assert.Equal(t, len(first.received()), 1)
+	assert.Equal(t, len(second.received()), 0)
+
+	time.Sleep(100 * time.Millisecond)
+
+	assert.Equal(t, len(first.received()), 1)
+	assert.Equal(t, len(second.received()), 1)
+	assert.Equal(t, second.received()[0][0].EscalationTier, 1)
+
+	resolved := Event{Status: StatusResolved, UUID: "a", Name: "a", Escalation: "critical"}
+	dispatcher.Dispatch(dispatcher.escalate(resolved))
+
+	assert.Equal(t, len(first.received()), 2)
+	assert.Equal(t, len(second.received()), 2)
+	assert.Equal(t, len(dispatcher.escalations), 0)
+}
+
+func TestEscalationSeverityChange(t *testing.T) {
+	first := &namedRecorder{name: "first"}
+	second := &namedRecorder{name: "second"}
+
+	policy, err := EscalationPolicyConfig{
+		Name: "critical",
+		Tiers: []EscalationTierConfig{
+			{Receivers: []string{"first"}},
+			{Delay: "100ms", Receivers: []string{"second"}},
+		},
+	}.Build([]string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := &Dispatcher{
+		Receivers:   []Notifier{first, second},
+		Policies:    map[string]*EscalationPolicy{"critical": policy},
+		firing:      make(map[string]*firingState),
+		escalations: make(map[string]*escalationState),
+	}
+
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "a", Escalation: "critical", Severity: "critical"}
+	dispatcher.Dispatch(dispatcher.escalate(firing))
+
+	time.Sleep(60 * time.Millisecond)
+
+	// The downgrade is sent to the current tier and doesn't restart the escalation
+	downgraded := firing
+	downgraded.Severity = "warning"
+	downgraded.PreviousSeverity = "critical"
+	dispatcher.Dispatch(dispatcher.escalate(downgraded))
+
+	assert.Equal(t, len(first.received()), 2)
+	assert.Equal(t, len(second.received()), 0)
+
+	time.Sleep(70 * time.Millisecond)
+
+	assert.Equal(t, len(first.received()), 2)
+	assert.Equal(t, len(second.received()), 1)
+	assert.Equal(t, second.received()[0][0].Severity, "warning")
+	assert.Equal(t, second.received()[0][0].PreviousSeverity, "")
+
+	dispatcher.stopEscalation("a")
+}
+
+func TestEscalationResumeOnAckExpiry(t *testing.T) {
+	first := &namedRecorder{name: "first"}
+	second := &namedRecorder{name: "second"}
+
+	policy, err := EscalationPolicyConfig{
+		Name: "critical",
+		Tiers: []EscalationTierConfig{
+			{Receivers: []string{"first"}},
+			{Delay: "60ms", Receivers: []string{"second"}},
+		},
+	}.Build([]string{"first", "second"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := &Dispatcher{
+		Receivers:   []Notifier{first, second},
+		Policies:    map[string]*EscalationPolicy{"critical": policy},
+		firing:      make(map[string]*firingState),
+		escalations: make(map[string]*escalationState),
+	}
+
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "a", Escalation: "critical"}
+	firing = dispatcher.escalate(firing)
+	dispatcher.track(firing, true)
+	dispatcher.Dispatch(firing)
+
+	stopped := func() bool {
+		dispatcher.stateMutex.Lock()
+		defer dispatcher.stateMutex.Unlock()
+
+		return dispatcher.escalations["a"].stopped()
+	}
+
+	expiresAt := time.Now().Add(30 * time.Millisecond)
+	dispatcher.acknowledge("a", &types.Acknowledgement{By: "ops", ExpiresAt: &expiresAt})
+	assert.Equal(t, stopped(), true)
+
+	// The escalation is resumed once the acknowledgement expires
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(t, len(second.received()), 1)
+
+	// The acknowledgement without the expiry keeps the escalation stopped
+	dispatcher.acknowledge("a", &types.Acknowledgement{By: "ops"})
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, stopped(), true)
+}
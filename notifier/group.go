@@ -0,0 +1,230 @@
+package notifier
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/wavix/w-alerts/utils"
+)
+
+// GroupNotifier is implemented by the receivers which can send several events
+// in a single digest message
+type GroupNotifier interface {
+	NotifyGroup(events []Event) error
+}
+
+type firingState struct {
+	event      Event
+	notifiedAt time.Time
+}
+
+// enqueue sends the events right away or, if group_wait is set, collects
+// them into a batch which is sent when the wait is over. The held events are
+// sent when the batch is released
+func (dispatcher *Dispatcher) enqueue(events ...Event) {
+	groupWait := dispatcher.groupWait()
+
+	dispatcher.stateMutex.Lock()
+	defer dispatcher.stateMutex.Unlock()
+
+	if groupWait == 0 && dispatcher.holding == 0 {
+		go dispatcher.Dispatch(events...)
+		return
+	}
+
+	if len(dispatcher.pending) == 0 && groupWait > 0 {
+		time.AfterFunc(groupWait, dispatcher.flush)
+	}
+
+	dispatcher.pending = append(dispatcher.pending, events...)
+}
+
+func (dispatcher *Dispatcher) groupWait() time.Duration {
+	dispatcher.Mutex.RLock()
+	defer dispatcher.Mutex.RUnlock()
+
+	return dispatcher.GroupWait
+}
+
+func (dispatcher *Dispatcher) flush() {
+	dispatcher.stateMutex.Lock()
+	if dispatcher.holding > 0 {
+		dispatcher.flushHeld = true
+		dispatcher.stateMutex.Unlock()
+		return
+	}
+
+	events := dispatcher.pending
+	dispatcher.pending = nil
+	dispatcher.stateMutex.Unlock()
+
+	if len(events) > 0 {
+		dispatcher.Dispatch(events...)
+	}
+}
+
+// Batch holds the events enqueued by fn and sends them when it returns
+func (dispatcher *Dispatcher) Batch(fn func()) {
+	dispatcher.stateMutex.Lock()
+	dispatcher.holding++
+	dispatcher.stateMutex.Unlock()
+
+	defer dispatcher.release()
+
+	fn()
+}
+
+func (dispatcher *Dispatcher) release() {
+	groupWait := dispatcher.groupWait()
+
+	dispatcher.stateMutex.Lock()
+	dispatcher.holding--
+
+	// With group_wait the events are sent by the timer, unless it has expired while holding
+	if dispatcher.holding > 0 || (groupWait > 0 && !dispatcher.flushHeld) {
+		dispatcher.stateMutex.Unlock()
+		return
+	}
+
+	events := dispatcher.pending
+	dispatcher.pending = nil
+	dispatcher.flushHeld = false
+	dispatcher.stateMutex.Unlock()
+
+	if len(events) > 0 {
+		go dispatcher.Dispatch(events...)
+	}
+}
+
+// track keeps the latest event of the firing rules for the repeat notifications
+func (dispatcher *Dispatcher) track(event Event, notified bool) {
+	dispatcher.stateMutex.Lock()
+	defer dispatcher.stateMutex.Unlock()
+
+	if !event.IsFiring() {
+		delete(dispatcher.firing, event.UUID)
+		return
+	}
+
+	state, exists := dispatcher.firing[event.UUID]
+	if !exists || notified {
+		dispatcher.firing[event.UUID] = &firingState{event: event, notifiedAt: time.Now()}
+		return
+	}
+
+	state.event = event
+}
+
+// IsFiring reports whether the rule is tracked as firing, i.e. its last notification was the firing one
+func IsFiring(uuid string) bool {
+	dispatcher.stateMutex.Lock()
+	defer dispatcher.stateMutex.Unlock()
+
+	_, exists := dispatcher.firing[uuid]
+	return exists
+}
+
+// repeat notifies again about the rules which keep firing longer than the interval, until stop is closed
+func (dispatcher *Dispatcher) repeat(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(min(interval, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			dispatcher.repeatDue(interval)
+		}
+	}
+}
+
+// repeatDue enqueues the repeat notifications of the firing rules which were not notified for the interval
+func (dispatcher *Dispatcher) repeatDue(interval time.Duration) {
+	events := make([]Event, 0)
+
+	dispatcher.stateMutex.Lock()
+	for _, state := range dispatcher.firing {
+		if time.Since(state.notifiedAt) < interval || state.event.Ack.IsActive() {
+			continue
+		}
+
+		state.notifiedAt = time.Now()
+
+		event := state.event
+		event.Repeat = true
+		event.Timestamp = time.Now().UTC()
+		events = append(events, event)
+	}
+	dispatcher.stateMutex.Unlock()
+
+	if len(events) > 0 {
+		dispatcher.enqueue(events...)
+	}
+}
+
+// send passes the events to the receiver, as a digest if the receiver supports it
+func send(receiver Notifier, events []Event) error {
+	if len(events) == 1 {
+		return receiver.Notify(events[0])
+	}
+
+	if groupNotifier, ok := receiver.(GroupNotifier); ok {
+		return groupNotifier.NotifyGroup(events)
+	}
+
+	errs := make([]error, 0)
+	for _, event := range events {
+		if err := receiver.Notify(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func logDelivery(receiver Notifier, events []Event, err error) {
+	log := utils.Logger.Context(receiver.Name())
+
+	if len(events) > 1 {
+		if err != nil {
+			log.Error().Msgf("Error sending digest of %d notifications: %v", len(events), err)
+			return
+		}
+
+		log.Info().Msgf("Digest of %d notifications sent", len(events))
+		return
+	}
+
+	if err != nil {
+		log.Error().Msgf("Error sending notification for '%s': %v", events[0].Name, err)
+		return
+	}
+
+	log.Info().Msgf("Notification sent for '%s' (%s)", events[0].Name, events[0].Status)
+}
+
+// DigestSummary returns a short description of the events (ex: 3 firing, 1 resolved)
+func DigestSummary(events []Event) string {
+	firing, resolved := 0, 0
+	for _, event := range events {
+		if event.IsFiring() {
+			firing++
+		} else {
+			resolved++
+		}
+	}
+
+	parts := make([]string, 0, 2)
+	if firing > 0 {
+		parts = append(parts, fmt.Sprintf("%d firing", firing))
+	}
+
+	if resolved > 0 {
+		parts = append(parts, fmt.Sprintf("%d resolved", resolved))
+	}
+
+	return fmt.Sprintf("%d alerts: %s", len(events), strings.Join(parts, ", "))
+}
@@ -0,0 +1,177 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+	"github.com/wavix/w-alerts/types"
+)
+
+type recorder struct {
+	groups [][]Event
+	mutex  sync.Mutex
+}
+
+func (recorder *recorder) Name() string {
+	return "recorder"
+}
+
+func (recorder *recorder) Notify(event Event) error {
+	return recorder.NotifyGroup([]Event{event})
+}
+
+func (recorder *recorder) NotifyGroup(events []Event) error {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	recorder.groups = append(recorder.groups, events)
+	return nil
+}
+
+func (recorder *recorder) received() [][]Event {
+	recorder.mutex.Lock()
+	defer recorder.mutex.Unlock()
+
+	return recorder.groups
+}
+
+func TestDispatcherGroupAndRepeat(t *testing.T) {
+	receiver := &recorder{}
+	dispatcher := &Dispatcher{
+		Receivers:      []Notifier{receiver},
+		GroupWait:      50 * time.Millisecond,
+		RepeatInterval: 100 * time.Millisecond,
+		firing:         make(map[string]*firingState),
+	}
+
+	for _, uuid := range []string{"a", "b", "c"} {
+		event := Event{Status: StatusFiring, UUID: uuid, Name: uuid}
+		dispatcher.track(event, true)
+		dispatcher.enqueue(event)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+
+	assert.Equal(t, len(receiver.received()), 1)
+	assert.Equal(t, len(receiver.received()[0]), 3)
+	assert.Equal(t, DigestSummary(receiver.received()[0]), "3 alerts: 3 firing")
+
+	resolved := Event{Status: StatusResolved, UUID: "c", Name: "c"}
+	dispatcher.track(resolved, true)
+
+	// The rules notified within the repeat interval are not repeated
+	dispatcher.repeatDue(dispatcher.RepeatInterval)
+	dispatcher.flush()
+	assert.Equal(t, len(receiver.received()), 1)
+
+	dispatcher.stateMutex.Lock()
+	for _, state := range dispatcher.firing {
+		state.notifiedAt = state.notifiedAt.Add(-dispatcher.RepeatInterval)
+	}
+	dispatcher.firing["b"].event.Ack = &types.Acknowledgement{By: "ops"}
+	dispatcher.stateMutex.Unlock()
+
+	// The acknowledged rule is not repeated
+	dispatcher.repeatDue(dispatcher.RepeatInterval)
+	dispatcher.flush()
+
+	repeated := receiver.received()[1]
+	assert.Equal(t, len(repeated), 1)
+	assert.Equal(t, repeated[0].UUID, "a")
+	assert.Equal(t, repeated[0].Repeat, true)
+}
+
+func TestBatchInhibition(t *testing.T) {
+	receiver := &recorder{}
+	source := "database"
+	sourceFiring := false
+
+	dispatcher := &Dispatcher{
+		Receivers: []Notifier{receiver},
+		Inhibitor: func(event Event) *string {
+			if event.UUID == "api" && sourceFiring {
+				return &source
+			}
+
+			return nil
+		},
+		firing: make(map[string]*firingState),
+	}
+
+	// The source fires after the target in the same batch
+	dispatcher.Batch(func() {
+		dispatcher.enqueue(Event{Status: StatusFiring, UUID: "api", Name: "API latency"})
+		sourceFiring = true
+		dispatcher.enqueue(Event{Status: StatusFiring, UUID: "database", Name: "Database down"})
+	})
+
+	time.Sleep(50 * time.Millisecond)
+
+	assert.Equal(t, len(receiver.received()), 1)
+	assert.Equal(t, len(receiver.received()[0]), 1)
+	assert.Equal(t, receiver.received()[0][0].UUID, "database")
+
+	// The resolved event of the inhibited rule is delivered
+	dispatcher.Dispatch(Event{Status: StatusResolved, UUID: "api", Name: "API latency"})
+	assert.Equal(t, len(receiver.received()), 2)
+	assert.Equal(t, receiver.received()[1][0].Status, StatusResolved)
+}
+
+func TestAnnounce(t *testing.T) {
+	receiver := &recorder{}
+
+	dispatcher.Mutex.Lock()
+	receivers := dispatcher.Receivers
+	dispatcher.Receivers = []Notifier{receiver}
+	dispatcher.Mutex.Unlock()
+
+	defer func() {
+		dispatcher.Mutex.Lock()
+		dispatcher.Receivers = receivers
+		dispatcher.Mutex.Unlock()
+	}()
+
+	// The flapping notification is sent once and is not tracked for the repeats
+	Announce(Event{Status: StatusFiring, UUID: "flapping", Name: "Latency", Flapping: true})
+	time.Sleep(20 * time.Millisecond)
+
+	assert.Equal(t, len(receiver.received()), 1)
+	assert.Equal(t, receiver.received()[0][0].Flapping, true)
+	assert.Equal(t, IsFiring("flapping"), false)
+
+	Notify(Event{Status: StatusFiring, UUID: "flapping", Name: "Latency"})
+	assert.Equal(t, IsFiring("flapping"), true)
+
+	Notify(Event{Status: StatusResolved, UUID: "flapping", Name: "Latency"})
+	assert.Equal(t, IsFiring("flapping"), false)
+}
+
+func TestSetupStopsLoops(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notifiers.json")
+	if err := os.WriteFile(path, []byte(`{"repeat_interval": "1h", "receivers": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Setup(SetupOptions{ConfigFile: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher.Mutex.RLock()
+	previous := dispatcher.stop
+	dispatcher.Mutex.RUnlock()
+
+	// The loops of the previous setup are stopped on reload
+	if err := Setup(SetupOptions{ConfigFile: path}); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-previous:
+	default:
+		t.Error("the loops of the previous setup are not stopped")
+	}
+}
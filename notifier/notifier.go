@@ -0,0 +1,628 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/wavix/w-alerts/types"
+	"github.com/wavix/w-alerts/utils"
+)
+
+const (
+	StatusFiring   = "firing"
+	StatusResolved = "resolved"
+)
+
+// Event describes a state transition of a rule (ok -> problem or problem -> ok)
+type Event struct {
+	Status           string                 `json:"status"`
+	UUID             string                 `json:"uuid"`
+	Name             string                 `json:"name"`
+	Scope            *string                `json:"scope"`
+	Description      string                 `json:"description"`
+	RulesResults     []interface{}          `json:"rules_results"`
+	Values           map[string]interface{} `json:"values"`
+	File             string                 `json:"file"`
+	IsStatic         bool                   `json:"is_static"`
+	OptIn            []string               `json:"opt_in"`
+	Severity         string                 `json:"severity"`
+	PreviousSeverity string                 `json:"previous_severity"` // Set when the severity of the firing rule changes
+	FiredAt          *time.Time             `json:"fired_at"`
+	ResolvedAt       *time.Time             `json:"resolved_at"`
+	Ack              *types.Acknowledgement `json:"ack"`
+	InhibitedBy      *string                `json:"inhibited_by"` // UUID of the firing rule which mutes the event
+	Escalation       string                 `json:"escalation"`
+	EscalationTier   int                    `json:"escalation_tier"`
+	Repeat           bool                   `json:"repeat"`   // The rule is still firing, the event was already sent
+	Flapping         bool                   `json:"flapping"` // The rule started flapping, its transitions are suppressed
+	Timestamp        time.Time              `json:"timestamp"`
+}
+
+type Notifier interface {
+	Name() string
+	Notify(event Event) error
+}
+
+// EvaluationNotifier is implemented by the receivers which need every
+// evaluation of a rule, not only the state transitions
+type EvaluationNotifier interface {
+	NotifyEvaluation(event Event) error
+}
+
+// Closer is implemented by the receivers which run in the background
+type Closer interface {
+	Close()
+}
+
+type Config struct {
+	GroupWait      string                   `json:"group_wait"`
+	RepeatInterval string                   `json:"repeat_interval"`
+	Outbox         OutboxConfig             `json:"outbox"`
+	Receivers      []ReceiverConfig         `json:"receivers"`
+	Escalations    []EscalationPolicyConfig `json:"escalation_policies"`
+	Schedules      []ScheduleConfig         `json:"schedules"`
+}
+
+type SetupOptions struct {
+	ConfigFile    string
+	RoutesFile    string
+	OutboxFile    string
+	OverridesFile string
+}
+
+type ReceiverConfig struct {
+	Name         string              `json:"name"`
+	Webhook      *WebhookConfig      `json:"webhook"`
+	Slack        *SlackConfig        `json:"slack"`
+	Mattermost   *SlackConfig        `json:"mattermost"`
+	Email        *EmailConfig        `json:"email"`
+	SMS          *SMSConfig          `json:"sms"`
+	Voice        *VoiceConfig        `json:"voice"`
+	PagerDuty    *PagerDutyConfig    `json:"pagerduty"`
+	Alertmanager *AlertmanagerConfig `json:"alertmanager"`
+}
+
+type Dispatcher struct {
+	Receivers      []Notifier
+	Route          *Route
+	Policies       map[string]*EscalationPolicy
+	Outbox         *Outbox
+	GroupWait      time.Duration
+	RepeatInterval time.Duration
+	Inhibitor      func(event Event) *string // Returns the UUID of the firing rule which mutes the event
+	Mutex          sync.RWMutex
+
+	pending     []Event
+	holding     int
+	flushHeld   bool
+	firing      map[string]*firingState
+	escalations map[string]*escalationState
+	ackTimers   map[string]*time.Timer // Resume the notifications when the acknowledgements expire
+	stateMutex  sync.Mutex
+	stop        chan struct{} // Stops the repeat and the retry loops of the current setup
+}
+
+var dispatcher = &Dispatcher{
+	firing:      make(map[string]*firingState),
+	escalations: make(map[string]*escalationState),
+	ackTimers:   make(map[string]*time.Timer),
+}
+
+func (event Event) IsFiring() bool {
+	return event.Status == StatusFiring
+}
+
+// Setup loads the receivers and the routes from the config files and makes them available for Notify
+func Setup(options SetupOptions) (err error) {
+	path := options.ConfigFile
+	routesPath := options.RoutesFile
+
+	if path == "" {
+		utils.Logger.Info().Msg("Notifications are disabled: NOTIFIERS_FILE is not set")
+		return nil
+	}
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		utils.Logger.Warn().Msgf("Notifications are disabled: %v does not exist", path)
+		return nil
+	}
+
+	config, err := LoadConfig(path)
+	if err != nil {
+		return err
+	}
+
+	err = SetupSchedules(config.Schedules, options.OverridesFile)
+	if err != nil {
+		return err
+	}
+
+	receivers, err := config.Build()
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if err != nil {
+			closeReceivers(receivers)
+		}
+	}()
+
+	names := make([]string, 0, len(receivers))
+	for _, receiver := range receivers {
+		names = append(names, receiver.Name())
+	}
+
+	policies := make(map[string]*EscalationPolicy)
+	for _, policyConfig := range config.Escalations {
+		policy, err := policyConfig.Build(names)
+		if err != nil {
+			return err
+		}
+
+		policies[policy.Name] = policy
+	}
+
+	var route *Route
+	if routesPath != "" {
+		route, err = LoadRoutes(routesPath)
+		if err != nil {
+			return err
+		}
+
+		err = route.Validate(names)
+		if err != nil {
+			return fmt.Errorf("invalid routes in %v: %w", routesPath, err)
+		}
+
+		utils.Logger.Info().Msgf("Notification routes loaded from %v", routesPath)
+	}
+
+	groupWait, err := parseDuration(config.GroupWait)
+	if err != nil {
+		return fmt.Errorf("error parsing group_wait: %w", err)
+	}
+
+	repeatInterval, err := parseDuration(config.RepeatInterval)
+	if err != nil {
+		return fmt.Errorf("error parsing repeat_interval: %w", err)
+	}
+
+	var outbox *Outbox
+	if options.OutboxFile != "" {
+		outbox, err = NewOutbox(options.OutboxFile, config.Outbox)
+		if err != nil {
+			return err
+		}
+
+		utils.Logger.Info().Msgf("Notification outbox loaded from %v (%d pending)", options.OutboxFile, len(outbox.Pending))
+	}
+
+	dispatcher.Mutex.Lock()
+	previous := dispatcher.Receivers
+	dispatcher.Receivers = receivers
+	dispatcher.Route = route
+	dispatcher.Policies = policies
+	dispatcher.Outbox = outbox
+	dispatcher.GroupWait = groupWait
+	dispatcher.RepeatInterval = repeatInterval
+
+	// The loops of the previous setup are stopped
+	if dispatcher.stop != nil {
+		close(dispatcher.stop)
+	}
+
+	stop := make(chan struct{})
+	dispatcher.stop = stop
+	dispatcher.Mutex.Unlock()
+
+	closeReceivers(previous)
+
+	if repeatInterval > 0 {
+		go dispatcher.repeat(repeatInterval, stop)
+	}
+
+	if outbox != nil {
+		go dispatcher.retry(outbox, stop)
+	}
+
+	utils.Logger.Info().Msgf("Loaded %d notification receivers from %v", len(receivers), path)
+
+	return nil
+}
+
+func LoadConfig(path string) (*Config, error) {
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var config Config
+	err = json.Unmarshal(jsonBytes, &config)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling notifiers config: %w", err)
+	}
+
+	return &config, nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+func (config *Config) Build() ([]Notifier, error) {
+	receivers := make([]Notifier, 0, len(config.Receivers))
+	names := make(map[string]struct{})
+
+	schedules := make([]string, 0, len(config.Schedules))
+	for _, schedule := range config.Schedules {
+		schedules = append(schedules, schedule.Name)
+	}
+
+	for _, receiver := range config.Receivers {
+		if receiver.Name == "" {
+			return nil, errors.New("receiver name is required")
+		}
+
+		if _, exists := names[receiver.Name]; exists {
+			return nil, fmt.Errorf("duplicate receiver name '%s'", receiver.Name)
+		}
+
+		if strings.HasPrefix(receiver.Name, ScheduleRecipientPrefix) {
+			return nil, fmt.Errorf("receiver name '%s' is reserved for the schedules", receiver.Name)
+		}
+		names[receiver.Name] = struct{}{}
+
+		err := validateRecipients(receiver.recipients(), schedules)
+		if err != nil {
+			return nil, fmt.Errorf("receiver '%s': %w", receiver.Name, err)
+		}
+
+		notifier, err := receiver.Build()
+		if err != nil {
+			closeReceivers(receivers)
+			return nil, fmt.Errorf("receiver '%s': %w", receiver.Name, err)
+		}
+
+		receivers = append(receivers, notifier)
+	}
+
+	for _, schedule := range config.Schedules {
+		if len(schedule.Receivers) == 0 {
+			continue
+		}
+
+		receiver, err := config.scheduleReceiver(schedule)
+		if err != nil {
+			closeReceivers(receivers)
+			return nil, err
+		}
+
+		receivers = append(receivers, receiver)
+	}
+
+	return receivers, nil
+}
+
+func (receiver ReceiverConfig) Build() (Notifier, error) {
+	if receiver.Webhook != nil {
+		return NewWebhook(receiver.Name, *receiver.Webhook)
+	}
+
+	if receiver.Slack != nil {
+		return NewSlack(receiver.Name, *receiver.Slack)
+	}
+
+	if receiver.Mattermost != nil {
+		return NewMattermost(receiver.Name, *receiver.Mattermost)
+	}
+
+	if receiver.Email != nil {
+		return NewEmail(receiver.Name, *receiver.Email)
+	}
+
+	if receiver.SMS != nil {
+		return NewSMS(receiver.Name, *receiver.SMS)
+	}
+
+	if receiver.Voice != nil {
+		return NewVoice(receiver.Name, *receiver.Voice)
+	}
+
+	if receiver.PagerDuty != nil {
+		return NewPagerDuty(receiver.Name, *receiver.PagerDuty)
+	}
+
+	if receiver.Alertmanager != nil {
+		return NewAlertmanager(receiver.Name, *receiver.Alertmanager)
+	}
+
+	return nil, errors.New("unsupported receiver type")
+}
+
+// closeReceivers stops the background work of the receivers which are not used anymore
+func closeReceivers(receivers []Notifier) {
+	for _, receiver := range receivers {
+		if closer, ok := receiver.(Closer); ok {
+			closer.Close()
+		}
+	}
+}
+
+// recipients returns the email addresses and phone numbers of the receiver
+func (receiver ReceiverConfig) recipients() []string {
+	recipients := make([]string, 0)
+
+	if receiver.Email != nil {
+		recipients = append(recipients, receiver.Email.To...)
+		for _, addresses := range receiver.Email.Scopes {
+			recipients = append(recipients, addresses...)
+		}
+		for _, addresses := range receiver.Email.Rules {
+			recipients = append(recipients, addresses...)
+		}
+	}
+
+	if receiver.SMS != nil {
+		recipients = append(recipients, receiver.SMS.To...)
+	}
+
+	if receiver.Voice != nil {
+		recipients = append(recipients, receiver.Voice.To...)
+	}
+
+	return recipients
+}
+
+// Select returns the receivers of the event. The rules with an escalation policy are sent
+// to the tier receivers, the other rules are routed. Without routes every receiver gets all events
+func (dispatcher *Dispatcher) Select(event Event) []Notifier {
+	policy := dispatcher.policy(event)
+
+	dispatcher.Mutex.RLock()
+	defer dispatcher.Mutex.RUnlock()
+
+	var names []string
+	switch {
+	case policy != nil:
+		names = policy.receivers(event.EscalationTier, !event.IsFiring())
+	case dispatcher.Route != nil:
+		names = dispatcher.Route.Select(event)
+	default:
+		return dispatcher.Receivers
+	}
+
+	receivers := make([]Notifier, 0, len(names))
+	for _, receiver := range dispatcher.Receivers {
+		if slices.Contains(names, receiver.Name()) {
+			receivers = append(receivers, receiver)
+		}
+	}
+
+	return receivers
+}
+
+// SetInhibitor sets the function which resolves the inhibition of the events when they are dispatched
+func SetInhibitor(inhibitor func(event Event) *string) {
+	dispatcher.Mutex.Lock()
+	defer dispatcher.Mutex.Unlock()
+
+	dispatcher.Inhibitor = inhibitor
+}
+
+// inhibitedBy returns the UUID of the firing rule which mutes the event at the moment
+func (dispatcher *Dispatcher) inhibitedBy(event Event) *string {
+	dispatcher.Mutex.RLock()
+	inhibitor := dispatcher.Inhibitor
+	dispatcher.Mutex.RUnlock()
+
+	if inhibitor == nil {
+		return event.InhibitedBy
+	}
+
+	return inhibitor(event)
+}
+
+// Dispatch sends the events to the selected receivers and waits for the deliveries to finish.
+// Each receiver gets all its events at once. With the outbox the failed deliveries are retried later.
+// The silences and the inhibition mute only the firing events, the resolved ones are always delivered
+func (dispatcher *Dispatcher) Dispatch(events ...Event) {
+	receivers := make([]Notifier, 0)
+	receiverEvents := make(map[string][]Event)
+
+	for _, event := range events {
+		if event.IsFiring() {
+			if silence := Silenced(event); silence != nil {
+				utils.Logger.Context(event.Name).Info().Msgf("Notification suppressed by silence %s", silence.ID)
+				continue
+			}
+
+			event.InhibitedBy = dispatcher.inhibitedBy(event)
+			if event.InhibitedBy != nil {
+				utils.Logger.Context(event.Name).Info().Msgf("Notification inhibited by rule %s", *event.InhibitedBy)
+				continue
+			}
+		}
+
+		for _, receiver := range dispatcher.Select(event) {
+			if _, exists := receiverEvents[receiver.Name()]; !exists {
+				receivers = append(receivers, receiver)
+			}
+
+			receiverEvents[receiver.Name()] = append(receiverEvents[receiver.Name()], event)
+		}
+	}
+
+	dispatcher.Mutex.RLock()
+	outbox := dispatcher.Outbox
+	dispatcher.Mutex.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, receiver := range receivers {
+		wg.Add(1)
+
+		go func(receiver Notifier, events []Event) {
+			defer wg.Done()
+
+			if outbox != nil {
+				dispatcher.deliver(outbox, outbox.Add(receiver.Name(), events))
+				return
+			}
+
+			err := send(receiver, events)
+			logDelivery(receiver, events, err)
+		}(receiver, receiverEvents[receiver.Name()])
+	}
+
+	wg.Wait()
+}
+
+// Receivers returns the names of the receivers which would get the event
+func Receivers(event Event) []string {
+	names := make([]string, 0)
+	for _, receiver := range dispatcher.Select(event) {
+		names = append(names, receiver.Name())
+	}
+
+	return names
+}
+
+// Acknowledge updates the acknowledgement of the firing rule. While the rule is
+// acknowledged, the repeat notifications, the escalation and the voice calls are stopped,
+// they are resumed when the acknowledgement is removed or expires
+func Acknowledge(uuid string, ack *types.Acknowledgement) {
+	dispatcher.acknowledge(uuid, ack)
+}
+
+func (dispatcher *Dispatcher) acknowledge(uuid string, ack *types.Acknowledgement) {
+	wasAcknowledged := false
+
+	dispatcher.stateMutex.Lock()
+	if state, exists := dispatcher.firing[uuid]; exists {
+		wasAcknowledged = state.event.Ack.IsActive()
+		state.event.Ack = ack
+	}
+
+	if timer, exists := dispatcher.ackTimers[uuid]; exists {
+		timer.Stop()
+		delete(dispatcher.ackTimers, uuid)
+	}
+
+	if ack.IsActive() && ack.ExpiresAt != nil {
+		if dispatcher.ackTimers == nil {
+			dispatcher.ackTimers = make(map[string]*time.Timer)
+		}
+
+		dispatcher.ackTimers[uuid] = time.AfterFunc(time.Until(*ack.ExpiresAt), func() {
+			dispatcher.resume(uuid)
+		})
+	}
+	dispatcher.stateMutex.Unlock()
+
+	if ack.IsActive() {
+		dispatcher.stopEscalation(uuid)
+	} else if wasAcknowledged {
+		dispatcher.resume(uuid)
+	}
+
+	dispatcher.Mutex.RLock()
+	defer dispatcher.Mutex.RUnlock()
+
+	for _, receiver := range expandReceivers(dispatcher.Receivers) {
+		switch receiver := receiver.(type) {
+		case *Voice:
+			if ack.IsActive() {
+				receiver.Stop(uuid)
+			}
+		case *Alertmanager:
+			receiver.Acknowledge(uuid, ack)
+		}
+	}
+}
+
+// resume restarts the escalation and the voice calls of the firing rule
+// once its acknowledgement is removed or expired
+func (dispatcher *Dispatcher) resume(uuid string) {
+	dispatcher.stateMutex.Lock()
+
+	firing, exists := dispatcher.firing[uuid]
+	if !exists || firing.event.Ack.IsActive() {
+		dispatcher.stateMutex.Unlock()
+		return
+	}
+
+	delete(dispatcher.ackTimers, uuid)
+	event := firing.event
+
+	if state, exists := dispatcher.escalations[uuid]; exists && state.stopped() {
+		// The escalation starts over, the next tiers are notified again after their delays
+		resumed := &escalationState{policy: state.policy, event: event, tier: state.tier, reached: state.reached, stop: make(chan struct{})}
+		dispatcher.escalations[uuid] = resumed
+
+		go dispatcher.runEscalation(resumed)
+	}
+	dispatcher.stateMutex.Unlock()
+
+	if Silenced(event) != nil || dispatcher.inhibitedBy(event) != nil {
+		return
+	}
+
+	for _, receiver := range expandReceivers(dispatcher.Select(event)) {
+		if voice, ok := receiver.(*Voice); ok {
+			if err := voice.Notify(event); err != nil {
+				utils.Logger.Context(voice.Name()).Error().Msgf("Error resuming calls for '%s': %v", event.Name, err)
+			}
+		}
+	}
+
+	utils.Logger.Context(event.Name).Info().Msg("Notifications are resumed, the acknowledgement is removed or expired")
+}
+
+// Notify sends the event to the configured receivers in the background
+func Notify(event Event) {
+	event = dispatcher.escalate(event)
+	dispatcher.track(event, true)
+	dispatcher.enqueue(event)
+}
+
+// Announce sends the informational event (ex: the rule started flapping) once,
+// without the escalation and the repeat notifications
+func Announce(event Event) {
+	dispatcher.enqueue(event)
+}
+
+// Batch holds the notifications sent by fn until it returns, so the inhibition of the events
+// is resolved after all the rules of the evaluation are processed
+func Batch(fn func()) {
+	dispatcher.Batch(fn)
+}
+
+// Evaluate passes the result of a rule evaluation without a state change to the receivers
+// which implement EvaluationNotifier
+func Evaluate(event Event) {
+	dispatcher.track(event, false)
+
+	for _, receiver := range dispatcher.Select(event) {
+		evaluationNotifier, ok := receiver.(EvaluationNotifier)
+		if !ok {
+			continue
+		}
+
+		go func(name string, evaluationNotifier EvaluationNotifier) {
+			err := evaluationNotifier.NotifyEvaluation(event)
+			if err != nil {
+				utils.Logger.Context(name).Error().Msgf("Error sending evaluation for '%s': %v", event.Name, err)
+			}
+		}(receiver.Name(), evaluationNotifier)
+	}
+}
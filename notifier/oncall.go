@@ -0,0 +1,533 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/wavix/w-alerts/utils"
+)
+
+// ScheduleRecipientPrefix is used in the recipient lists of the receivers
+// to send the notification to the member who is on call (ex: "schedule:primary")
+const ScheduleRecipientPrefix = "schedule:"
+
+// ErrOverrideNotFound is returned by RemoveOverride when there is no override with the id
+var ErrOverrideNotFound = errors.New("override not found")
+
+var scheduleWeekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+const (
+	ContactEmail = "email"
+	ContactPhone = "phone"
+)
+
+type ScheduleMember struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Phone string `json:"phone"`
+}
+
+type ScheduleConfig struct {
+	Name        string           `json:"name"`
+	Timezone    string           `json:"timezone"`
+	Rotation    string           `json:"rotation"`
+	HandoffDay  string           `json:"handoff_day"`
+	HandoffTime string           `json:"handoff_time"`
+	Start       string           `json:"start"`
+	Members     []ScheduleMember `json:"members"`
+	Overrides   []Override       `json:"overrides"`
+	Receivers   []string         `json:"receivers"` // Email, SMS and voice receivers used by the "schedule:<name>" route receiver
+}
+
+// Schedule is an on-call rotation. The members take turns in order, the
+// first member is on call from the first handoff on or after the start date
+type Schedule struct {
+	Name      string
+	Members   []ScheduleMember
+	Overrides []Override
+
+	location   *time.Location
+	shiftDays  int
+	handoffDay int
+	handoff    int
+	start      time.Time
+}
+
+// Override replaces the member of the schedule between Start and End (ex: for swaps)
+type Override struct {
+	ID        string    `json:"id"`
+	Schedule  string    `json:"schedule"`
+	Member    string    `json:"member"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	CreatedBy string    `json:"created_by"`
+
+	// static overrides come from the config and are not persisted
+	static bool
+}
+
+// OnCall is the member who is on call now
+type OnCall struct {
+	Schedule string         `json:"schedule"`
+	Member   ScheduleMember `json:"member"`
+	Until    time.Time      `json:"until"`
+	Override *string        `json:"override"`
+}
+
+type Schedules struct {
+	Schedules     map[string]*Schedule
+	OverridesPath string
+	Mutex         sync.RWMutex
+}
+
+var schedules = &Schedules{Schedules: make(map[string]*Schedule)}
+
+func (config ScheduleConfig) Build() (*Schedule, error) {
+	if config.Name == "" {
+		return nil, errors.New("schedule name is required")
+	}
+
+	if len(config.Members) == 0 {
+		return nil, fmt.Errorf("schedule '%s' has no members", config.Name)
+	}
+
+	schedule := &Schedule{
+		Name:      config.Name,
+		Members:   config.Members,
+		Overrides: make([]Override, 0),
+		location:  time.UTC,
+		shiftDays: 7,
+	}
+
+	var err error
+	if config.Timezone != "" {
+		if schedule.location, err = time.LoadLocation(config.Timezone); err != nil {
+			return nil, fmt.Errorf("schedule '%s': invalid timezone: %w", config.Name, err)
+		}
+	}
+
+	switch config.Rotation {
+	case "", "weekly":
+		handoffDay := strings.ToLower(config.HandoffDay)
+		if handoffDay == "" {
+			handoffDay = "mon"
+		}
+
+		schedule.handoffDay = slices.Index(scheduleWeekdays, handoffDay)
+		if schedule.handoffDay == -1 {
+			return nil, fmt.Errorf("schedule '%s': invalid handoff day '%s'", config.Name, config.HandoffDay)
+		}
+	case "daily":
+		schedule.shiftDays = 1
+	default:
+		return nil, fmt.Errorf("schedule '%s': unsupported rotation '%s'", config.Name, config.Rotation)
+	}
+
+	handoffTime := config.HandoffTime
+	if handoffTime == "" {
+		handoffTime = "09:00"
+	}
+
+	clock, err := time.Parse("15:04", handoffTime)
+	if err != nil {
+		return nil, fmt.Errorf("schedule '%s': invalid handoff time '%s'", config.Name, config.HandoffTime)
+	}
+	schedule.handoff = clock.Hour()*60 + clock.Minute()
+
+	start, err := time.ParseInLocation("2006-01-02", config.Start, schedule.location)
+	if err != nil {
+		return nil, fmt.Errorf("schedule '%s': invalid start date '%s'", config.Name, config.Start)
+	}
+	schedule.start = schedule.shiftStart(start.AddDate(0, 0, schedule.shiftDays).Add(-time.Nanosecond))
+
+	for _, override := range config.Overrides {
+		override.Schedule = config.Name
+		override.static = true
+		if err = schedule.AddOverride(&override); err != nil {
+			return nil, err
+		}
+	}
+
+	return schedule, nil
+}
+
+// shiftStart returns the last handoff before the time
+func (schedule *Schedule) shiftStart(t time.Time) time.Time {
+	local := t.In(schedule.location)
+
+	daysBack := 0
+	if schedule.shiftDays == 7 {
+		daysBack = (int(local.Weekday()) - schedule.handoffDay + 7) % 7
+	}
+
+	start := time.Date(local.Year(), local.Month(), local.Day()-daysBack, schedule.handoff/60, schedule.handoff%60, 0, 0, schedule.location)
+	if start.After(local) {
+		start = start.AddDate(0, 0, -schedule.shiftDays)
+	}
+
+	return start
+}
+
+func (schedule *Schedule) member(name string) *ScheduleMember {
+	for i := range schedule.Members {
+		if schedule.Members[i].Name == name {
+			return &schedule.Members[i]
+		}
+	}
+
+	return nil
+}
+
+func (schedule *Schedule) AddOverride(override *Override) error {
+	if schedule.member(override.Member) == nil {
+		return fmt.Errorf("schedule '%s' has no member '%s'", schedule.Name, override.Member)
+	}
+
+	if !override.End.After(override.Start) {
+		return errors.New("override end must be after start")
+	}
+
+	if override.ID == "" {
+		id, err := uuid.NewV4()
+		if err != nil {
+			return err
+		}
+
+		override.ID = id.String()
+	}
+
+	schedule.Overrides = append(schedule.Overrides, *override)
+
+	return nil
+}
+
+// OnCall returns the member who is on call at the time
+func (schedule *Schedule) OnCall(now time.Time) OnCall {
+	shift := schedule.shiftStart(now)
+	until := shift.AddDate(0, 0, schedule.shiftDays)
+
+	for _, override := range schedule.Overrides {
+		if !now.Before(override.Start) && now.Before(override.End) {
+			id := override.ID
+			return OnCall{Schedule: schedule.Name, Member: *schedule.member(override.Member), Until: override.End, Override: &id}
+		}
+	}
+
+	// Calendar days, so the daylight saving changes don't shift the rotation
+	days := int(time.Date(shift.Year(), shift.Month(), shift.Day(), 0, 0, 0, 0, time.UTC).
+		Sub(time.Date(schedule.start.Year(), schedule.start.Month(), schedule.start.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+
+	count := len(schedule.Members)
+	index := ((days/schedule.shiftDays)%count + count) % count
+
+	return OnCall{Schedule: schedule.Name, Member: schedule.Members[index], Until: until}
+}
+
+// SetupSchedules makes the schedules available for the receivers and loads the overrides created by API
+func SetupSchedules(configs []ScheduleConfig, overridesPath string) error {
+	built := make(map[string]*Schedule)
+	for _, config := range configs {
+		schedule, err := config.Build()
+		if err != nil {
+			return err
+		}
+
+		if _, exists := built[schedule.Name]; exists {
+			return fmt.Errorf("duplicate schedule name '%s'", schedule.Name)
+		}
+
+		built[schedule.Name] = schedule
+	}
+
+	if overridesPath != "" {
+		if _, err := os.Stat(overridesPath); err == nil {
+			jsonBytes, err := os.ReadFile(overridesPath)
+			if err != nil {
+				return err
+			}
+
+			var overrides []Override
+			if err = json.Unmarshal(jsonBytes, &overrides); err != nil {
+				return fmt.Errorf("error unmarshalling on-call overrides: %w", err)
+			}
+
+			for _, override := range overrides {
+				schedule, exists := built[override.Schedule]
+				if !exists {
+					utils.Logger.Warn().Msgf("Skip on-call override %s of unknown schedule '%s'", override.ID, override.Schedule)
+					continue
+				}
+
+				if err = schedule.AddOverride(&override); err != nil {
+					utils.Logger.Warn().Msgf("Skip on-call override %s: %v", override.ID, err)
+				}
+			}
+		}
+	}
+
+	schedules.Mutex.Lock()
+	schedules.Schedules = built
+	schedules.OverridesPath = overridesPath
+	schedules.Mutex.Unlock()
+
+	return nil
+}
+
+// ResolveRecipients replaces the "schedule:<name>" recipients with the contact of the member on call
+func ResolveRecipients(recipients []string, contact string) []string {
+	schedules.Mutex.RLock()
+	defer schedules.Mutex.RUnlock()
+
+	now := time.Now()
+	resolved := make([]string, 0, len(recipients))
+	for _, recipient := range recipients {
+		if !strings.HasPrefix(recipient, ScheduleRecipientPrefix) {
+			resolved = append(resolved, recipient)
+			continue
+		}
+
+		schedule, exists := schedules.Schedules[strings.TrimPrefix(recipient, ScheduleRecipientPrefix)]
+		if !exists {
+			utils.Logger.Warn().Msgf("Unknown on-call schedule '%s'", recipient)
+			continue
+		}
+
+		member := schedule.OnCall(now).Member
+		value := member.Email
+		if contact == ContactPhone {
+			value = member.Phone
+		}
+
+		if value == "" {
+			utils.Logger.Warn().Msgf("On-call member '%s' of '%s' has no %s", member.Name, schedule.Name, contact)
+			continue
+		}
+
+		resolved = append(resolved, value)
+	}
+
+	return resolved
+}
+
+// validateRecipients checks that the schedules used in the recipient lists exist
+func validateRecipients(recipients []string, names []string) error {
+	for _, recipient := range recipients {
+		if !strings.HasPrefix(recipient, ScheduleRecipientPrefix) {
+			continue
+		}
+
+		if !slices.Contains(names, strings.TrimPrefix(recipient, ScheduleRecipientPrefix)) {
+			return fmt.Errorf("unknown on-call schedule '%s'", recipient)
+		}
+	}
+
+	return nil
+}
+
+// ScheduleReceiver notifies the member who is on call through the email, SMS and voice
+// receivers of the schedule. It's used in the routes and the escalation tiers as "schedule:<name>"
+type ScheduleReceiver struct {
+	name      string
+	receivers []Notifier
+}
+
+func (receiver *ScheduleReceiver) Name() string {
+	return receiver.name
+}
+
+func (receiver *ScheduleReceiver) Notify(event Event) error {
+	return receiver.NotifyGroup([]Event{event})
+}
+
+func (receiver *ScheduleReceiver) NotifyGroup(events []Event) error {
+	errs := make([]error, 0)
+	for _, notifier := range receiver.receivers {
+		if err := send(notifier, events); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", notifier.Name(), err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close closes the receivers of the schedule
+func (receiver *ScheduleReceiver) Close() {
+	closeReceivers(receiver.receivers)
+}
+
+// scheduleReceiver builds the copies of the receivers of the schedule which send to the member on call
+func (config *Config) scheduleReceiver(schedule ScheduleConfig) (*ScheduleReceiver, error) {
+	name := ScheduleRecipientPrefix + schedule.Name
+	recipients := []string{name}
+	receiver := &ScheduleReceiver{name: name, receivers: make([]Notifier, 0, len(schedule.Receivers))}
+
+	for _, receiverName := range schedule.Receivers {
+		index := slices.IndexFunc(config.Receivers, func(receiverConfig ReceiverConfig) bool {
+			return receiverConfig.Name == receiverName
+		})
+		if index == -1 {
+			receiver.Close()
+			return nil, fmt.Errorf("schedule '%s': unknown receiver '%s'", schedule.Name, receiverName)
+		}
+
+		source := config.Receivers[index]
+		receiverConfig := ReceiverConfig{Name: name + "/" + receiverName}
+
+		switch {
+		case source.Email != nil:
+			email := *source.Email
+			email.To, email.Scopes, email.Rules = recipients, nil, nil
+			receiverConfig.Email = &email
+		case source.SMS != nil:
+			sms := *source.SMS
+			sms.To = recipients
+			receiverConfig.SMS = &sms
+		case source.Voice != nil:
+			voice := *source.Voice
+			voice.To = recipients
+			receiverConfig.Voice = &voice
+		default:
+			receiver.Close()
+			return nil, fmt.Errorf("schedule '%s': receiver '%s' must be an email, SMS or voice receiver", schedule.Name, receiverName)
+		}
+
+		notifier, err := receiverConfig.Build()
+		if err != nil {
+			receiver.Close()
+			return nil, fmt.Errorf("schedule '%s': receiver '%s': %w", schedule.Name, receiverName, err)
+		}
+
+		receiver.receivers = append(receiver.receivers, notifier)
+	}
+
+	return receiver, nil
+}
+
+// expandReceivers returns the receivers with the receivers of the schedules in place of the schedules
+func expandReceivers(receivers []Notifier) []Notifier {
+	expanded := make([]Notifier, 0, len(receivers))
+	for _, receiver := range receivers {
+		if scheduleReceiver, ok := receiver.(*ScheduleReceiver); ok {
+			expanded = append(expanded, scheduleReceiver.receivers...)
+			continue
+		}
+
+		expanded = append(expanded, receiver)
+	}
+
+	return expanded
+}
+
+func ListOnCall() []OnCall {
+	schedules.Mutex.RLock()
+	defer schedules.Mutex.RUnlock()
+
+	now := time.Now()
+	result := make([]OnCall, 0, len(schedules.Schedules))
+	for _, schedule := range schedules.Schedules {
+		result = append(result, schedule.OnCall(now))
+	}
+
+	slices.SortFunc(result, func(a, b OnCall) int {
+		return strings.Compare(a.Schedule, b.Schedule)
+	})
+
+	return result
+}
+
+func ListOverrides() []Override {
+	schedules.Mutex.RLock()
+	defer schedules.Mutex.RUnlock()
+
+	result := make([]Override, 0)
+	for _, schedule := range schedules.Schedules {
+		result = append(result, schedule.Overrides...)
+	}
+
+	slices.SortFunc(result, func(a, b Override) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return result
+}
+
+// AddOverride creates an override for the schedule and persists the overrides
+func AddOverride(override Override) (*Override, error) {
+	schedules.Mutex.Lock()
+	defer schedules.Mutex.Unlock()
+
+	schedule, exists := schedules.Schedules[override.Schedule]
+	if !exists {
+		return nil, fmt.Errorf("unknown schedule '%s'", override.Schedule)
+	}
+
+	override.ID = ""
+	override.static = false
+	if err := schedule.AddOverride(&override); err != nil {
+		return nil, err
+	}
+
+	schedules.save()
+
+	return &override, nil
+}
+
+// RemoveOverride removes the override created through the API,
+// the overrides from the config can't be removed
+func RemoveOverride(id string) error {
+	schedules.Mutex.Lock()
+	defer schedules.Mutex.Unlock()
+
+	for _, schedule := range schedules.Schedules {
+		index := slices.IndexFunc(schedule.Overrides, func(override Override) bool {
+			return override.ID == id
+		})
+
+		if index == -1 {
+			continue
+		}
+
+		if schedule.Overrides[index].static {
+			return fmt.Errorf("override %s of '%s' is defined in the config", id, schedule.Name)
+		}
+
+		schedule.Overrides = slices.Delete(schedule.Overrides, index, index+1)
+		schedules.save()
+		return nil
+	}
+
+	return ErrOverrideNotFound
+}
+
+func (schedules *Schedules) save() {
+	if schedules.OverridesPath == "" {
+		return
+	}
+
+	overrides := make([]Override, 0)
+	for _, schedule := range schedules.Schedules {
+		for _, override := range schedule.Overrides {
+			if !override.static {
+				overrides = append(overrides, override)
+			}
+		}
+	}
+
+	jsonBytes, err := json.Marshal(overrides)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error marshalling on-call overrides: %v", err)
+		return
+	}
+
+	err = os.WriteFile(schedules.OverridesPath, jsonBytes, 0644)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error writing on-call overrides: %v", err)
+	}
+}
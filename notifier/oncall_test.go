@@ -0,0 +1,151 @@
+package notifier
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+)
+
+func TestScheduleRotation(t *testing.T) {
+	schedule, err := ScheduleConfig{
+		Name:        "primary",
+		Timezone:    "Europe/Berlin",
+		HandoffDay:  "mon",
+		HandoffTime: "09:00",
+		Start:       "2024-01-01",
+		Members:     []ScheduleMember{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+	}.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	at := func(value string) time.Time {
+		t, _ := time.ParseInLocation("2006-01-02 15:04", value, berlin)
+		return t
+	}
+
+	assert.Equal(t, schedule.OnCall(at("2024-01-01 08:59")).Member.Name, "c")
+	assert.Equal(t, schedule.OnCall(at("2024-01-01 09:00")).Member.Name, "a")
+	assert.Equal(t, schedule.OnCall(at("2024-01-07 23:00")).Member.Name, "a")
+	assert.Equal(t, schedule.OnCall(at("2024-01-08 10:00")).Member.Name, "b")
+	assert.Equal(t, schedule.OnCall(at("2024-01-08 10:00")).Until, at("2024-01-15 09:00"))
+
+	// The handoff time is kept after the daylight saving change
+	assert.Equal(t, schedule.OnCall(at("2024-04-01 08:59")).Member.Name, "a")
+	assert.Equal(t, schedule.OnCall(at("2024-04-01 09:00")).Member.Name, "b")
+}
+
+func TestScheduleOverrides(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "oncall-overrides.json")
+	configs := []ScheduleConfig{{
+		Name:     "primary",
+		Rotation: "daily",
+		Start:    "2024-01-01",
+		Members: []ScheduleMember{
+			{Name: "a", Email: "a@example.com", Phone: "+1001"},
+			{Name: "b", Email: "b@example.com", Phone: "+1002"},
+		},
+		Overrides: []Override{{ID: "static", Member: "a", Start: time.Date(2100, 1, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2100, 1, 2, 0, 0, 0, 0, time.UTC)}},
+	}}
+
+	if err := SetupSchedules(configs, path); err != nil {
+		t.Fatal(err)
+	}
+	defer SetupSchedules(nil, "")
+
+	onCall := ListOnCall()[0].Member
+	other := "a"
+	if onCall.Name == "a" {
+		other = "b"
+	}
+
+	_, err := AddOverride(Override{Schedule: "primary", Member: "unknown", Start: time.Now(), End: time.Now().Add(time.Hour)})
+	assert.NotEqual(t, err, nil)
+
+	override, err := AddOverride(Override{Schedule: "primary", Member: other, Start: time.Now().Add(-time.Minute), End: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, ListOnCall()[0].Member.Name, other)
+	assert.Equal(t, *ListOnCall()[0].Override, override.ID)
+	assert.Equal(t, ResolveRecipients([]string{"ops@example.com", "schedule:primary"}, ContactEmail), []string{"ops@example.com", other + "@example.com"})
+
+	// Overrides are restored from the file
+	if err = SetupSchedules(configs, path); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, ListOnCall()[0].Member.Name, other)
+	assert.Equal(t, RemoveOverride(override.ID), nil)
+	assert.Equal(t, RemoveOverride(override.ID), ErrOverrideNotFound)
+
+	// The overrides from the config can't be removed
+	assert.NotEqual(t, RemoveOverride("static"), nil)
+	assert.Equal(t, len(ListOverrides()), 1)
+	assert.Equal(t, ListOnCall()[0].Member.Name, onCall.Name)
+}
+
+func TestScheduleReceiver(t *testing.T) {
+	received := make(chan map[string]string, 1)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			t.Error(err)
+		}
+
+		received <- message
+	}))
+	defer server.Close()
+
+	config := Config{
+		Receivers: []ReceiverConfig{
+			{Name: "sms", SMS: &SMSConfig{Url: server.URL, To: []string{"+100"}}},
+			{Name: "webhook", Webhook: &WebhookConfig{Url: server.URL}},
+		},
+		Schedules: []ScheduleConfig{{
+			Name:      "primary",
+			Start:     "2024-01-01",
+			Members:   []ScheduleMember{{Name: "a", Phone: "+1001"}},
+			Receivers: []string{"sms"},
+		}},
+	}
+
+	if err := SetupSchedules(config.Schedules, ""); err != nil {
+		t.Fatal(err)
+	}
+	defer SetupSchedules(nil, "")
+
+	receivers, err := config.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, receivers[2].Name(), "schedule:primary")
+
+	// The schedule is a route receiver
+	route := &Route{Receivers: []string{"schedule:primary"}}
+	assert.Equal(t, route.Validate([]string{"sms", "webhook", "schedule:primary"}), nil)
+
+	// The member on call gets the message instead of the recipients of the receiver
+	event := Event{Status: StatusFiring, UUID: "a", Name: "Error rate", OptIn: []string{OptInSMS}}
+	assert.Equal(t, receivers[2].Notify(event), nil)
+	assert.Equal(t, (<-received)["to"], "+1001")
+
+	// Only the receivers which send to the members can be used
+	config.Schedules[0].Receivers = []string{"webhook"}
+	_, err = config.Build()
+	assert.NotEqual(t, err, nil)
+
+	config.Receivers[1].Name = "schedule:webhook"
+	config.Schedules[0].Receivers = nil
+	_, err = config.Build()
+	assert.NotEqual(t, err, nil)
+}
@@ -0,0 +1,364 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/wavix/w-alerts/utils"
+)
+
+const outboxRetryTick = 10 * time.Second
+
+type OutboxConfig struct {
+	MaxAttempts    int    `json:"max_attempts"`
+	Backoff        string `json:"backoff"`
+	MaxBackoff     string `json:"max_backoff"`
+	MaxDeadLetters int    `json:"max_dead_letters"`
+}
+
+// OutboxEntry is a notification for a receiver which is not delivered yet
+type OutboxEntry struct {
+	ID            string    `json:"id"`
+	Receiver      string    `json:"receiver"`
+	Events        []Event   `json:"events"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error"`
+	NextAttemptAt time.Time `json:"next_attempt_at"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	inFlight bool
+}
+
+// Outbox keeps the undelivered notifications on disk, so they survive restarts.
+// Failed deliveries are retried with exponential backoff and moved to the
+// dead letters after max_attempts, only the last max_dead_letters are kept.
+// An event is dropped from the outbox once a newer event of the same rule is
+// added for the same receiver, so a stale retry never overrides a newer state
+type Outbox struct {
+	Path        string         `json:"-"`
+	Pending     []*OutboxEntry `json:"pending"`
+	DeadLetters []*OutboxEntry `json:"dead_letters"`
+
+	maxAttempts    int
+	backoff        time.Duration
+	maxBackoff     time.Duration
+	maxDeadLetters int
+	latest         map[string]time.Time // timestamp of the newest event by receiver and rule uuid
+	mutex          sync.Mutex
+}
+
+func NewOutbox(path string, config OutboxConfig) (*Outbox, error) {
+	outbox := &Outbox{
+		Path:           path,
+		Pending:        make([]*OutboxEntry, 0),
+		DeadLetters:    make([]*OutboxEntry, 0),
+		maxAttempts:    10,
+		backoff:        30 * time.Second,
+		maxBackoff:     time.Hour,
+		maxDeadLetters: 100,
+		latest:         make(map[string]time.Time),
+	}
+
+	if config.MaxAttempts > 0 {
+		outbox.maxAttempts = config.MaxAttempts
+	}
+
+	if config.MaxDeadLetters > 0 {
+		outbox.maxDeadLetters = config.MaxDeadLetters
+	}
+
+	var err error
+	if config.Backoff != "" {
+		if outbox.backoff, err = time.ParseDuration(config.Backoff); err != nil {
+			return nil, fmt.Errorf("error parsing outbox backoff: %w", err)
+		}
+	}
+
+	if config.MaxBackoff != "" {
+		if outbox.maxBackoff, err = time.ParseDuration(config.MaxBackoff); err != nil {
+			return nil, fmt.Errorf("error parsing outbox max backoff: %w", err)
+		}
+	}
+
+	if _, err = os.Stat(path); os.IsNotExist(err) {
+		return outbox, nil
+	}
+
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = json.Unmarshal(jsonBytes, outbox)
+	if err != nil {
+		// The corrupt file is kept aside for the investigation, the outbox starts empty
+		quarantine := fmt.Sprintf("%s.corrupt-%d", path, time.Now().Unix())
+		utils.Logger.Error().Msgf("Error unmarshalling outbox, moving it to %v: %v", quarantine, err)
+
+		if err = os.Rename(path, quarantine); err != nil {
+			return nil, fmt.Errorf("error moving corrupt outbox: %w", err)
+		}
+
+		outbox.Pending = make([]*OutboxEntry, 0)
+		outbox.DeadLetters = make([]*OutboxEntry, 0)
+	}
+
+	for _, entry := range outbox.Pending {
+		outbox.track(entry)
+	}
+
+	return outbox, nil
+}
+
+// Add stores the notification before the first delivery attempt
+func (outbox *Outbox) Add(receiver string, events []Event) *OutboxEntry {
+	id, err := uuid.NewV4()
+	if err != nil {
+		panic(err)
+	}
+
+	now := time.Now().UTC()
+	entry := &OutboxEntry{
+		ID:            id.String(),
+		Receiver:      receiver,
+		Events:        events,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+		inFlight:      true,
+	}
+
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	outbox.track(entry)
+	outbox.supersede()
+
+	outbox.Pending = append(outbox.Pending, entry)
+	outbox.save()
+
+	return entry
+}
+
+// Due returns the entries which should be retried now and marks them as in flight
+func (outbox *Outbox) Due() []*OutboxEntry {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	now := time.Now()
+	entries := make([]*OutboxEntry, 0)
+	for _, entry := range outbox.Pending {
+		if entry.inFlight || entry.NextAttemptAt.After(now) {
+			continue
+		}
+
+		entry.inFlight = true
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// Done removes the delivered entry
+func (outbox *Outbox) Done(entry *OutboxEntry) {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	outbox.remove(entry)
+	outbox.save()
+}
+
+// Fail schedules the next attempt or moves the entry to the dead letters
+func (outbox *Outbox) Fail(entry *OutboxEntry, err error) {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	entry.inFlight = false
+	entry.Attempts++
+	entry.LastError = err.Error()
+
+	if outbox.drop(entry) {
+		utils.Logger.Context(entry.Receiver).Info().Msgf("Notification %s is superseded by a newer one", entry.ID)
+		outbox.remove(entry)
+	} else if entry.Attempts >= outbox.maxAttempts {
+		outbox.remove(entry)
+		outbox.DeadLetters = append(outbox.DeadLetters, entry)
+		if len(outbox.DeadLetters) > outbox.maxDeadLetters {
+			outbox.DeadLetters = outbox.DeadLetters[len(outbox.DeadLetters)-outbox.maxDeadLetters:]
+		}
+
+		utils.Logger.Context(entry.Receiver).Error().Msgf("Notification %s moved to dead letters after %d attempts", entry.ID, entry.Attempts)
+	} else {
+		backoff := outbox.backoff << (entry.Attempts - 1)
+		if backoff > outbox.maxBackoff || backoff <= 0 {
+			backoff = outbox.maxBackoff
+		}
+
+		entry.NextAttemptAt = time.Now().UTC().Add(backoff)
+	}
+
+	outbox.save()
+}
+
+func (outbox *Outbox) remove(entry *OutboxEntry) {
+	outbox.Pending = slices.DeleteFunc(outbox.Pending, func(e *OutboxEntry) bool {
+		return e.ID == entry.ID
+	})
+}
+
+func outboxKey(receiver string, event Event) string {
+	return receiver + "/" + event.UUID
+}
+
+// track remembers the timestamps of the newest events of the entry
+func (outbox *Outbox) track(entry *OutboxEntry) {
+	for _, event := range entry.Events {
+		key := outboxKey(entry.Receiver, event)
+		if event.Timestamp.After(outbox.latest[key]) {
+			outbox.latest[key] = event.Timestamp
+		}
+	}
+}
+
+// drop removes the superseded events from the entry and reports whether
+// nothing is left to deliver
+func (outbox *Outbox) drop(entry *OutboxEntry) bool {
+	// The events may be shared with the entries of the other receivers, so they are copied
+	events := make([]Event, 0, len(entry.Events))
+	for _, event := range entry.Events {
+		if !event.Timestamp.Before(outbox.latest[outboxKey(entry.Receiver, event)]) {
+			events = append(events, event)
+		}
+	}
+
+	entry.Events = events
+	return len(events) == 0
+}
+
+// supersede drops the superseded events from the entries waiting for a retry,
+// the entries in flight are handled by Fail
+func (outbox *Outbox) supersede() {
+	outbox.Pending = slices.DeleteFunc(outbox.Pending, func(entry *OutboxEntry) bool {
+		return !entry.inFlight && outbox.drop(entry)
+	})
+}
+
+// Snapshot returns the number of pending entries and a copy of the dead letters
+func (outbox *Outbox) Snapshot() (int, []OutboxEntry) {
+	outbox.mutex.Lock()
+	defer outbox.mutex.Unlock()
+
+	deadLetters := make([]OutboxEntry, 0, len(outbox.DeadLetters))
+	for _, entry := range outbox.DeadLetters {
+		deadLetters = append(deadLetters, *entry)
+	}
+
+	return len(outbox.Pending), deadLetters
+}
+
+func (outbox *Outbox) save() {
+	jsonBytes, err := json.Marshal(outbox)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error marshalling outbox: %v", err)
+		return
+	}
+
+	err = writeFileAtomic(outbox.Path, jsonBytes)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error writing outbox: %v", err)
+	}
+}
+
+// writeFileAtomic writes a temporary file next to the path and renames it,
+// so the file is never left partially written
+func writeFileAtomic(path string, data []byte) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name()) // nolint:errcheck
+
+	if _, err = file.Write(data); err != nil {
+		file.Close() // nolint:errcheck
+		return err
+	}
+
+	if err = file.Chmod(0644); err != nil {
+		file.Close() // nolint:errcheck
+		return err
+	}
+
+	if err = file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// deliver sends the entry to its receiver and updates the outbox with the result
+func (dispatcher *Dispatcher) deliver(outbox *Outbox, entry *OutboxEntry) {
+	receiver := dispatcher.receiver(entry.Receiver)
+	if receiver == nil {
+		outbox.Fail(entry, fmt.Errorf("unknown receiver '%s'", entry.Receiver))
+		return
+	}
+
+	err := send(receiver, entry.Events)
+	logDelivery(receiver, entry.Events, err)
+
+	if err != nil {
+		outbox.Fail(entry, err)
+		return
+	}
+
+	outbox.Done(entry)
+}
+
+func (dispatcher *Dispatcher) receiver(name string) Notifier {
+	dispatcher.Mutex.RLock()
+	defer dispatcher.Mutex.RUnlock()
+
+	for _, receiver := range dispatcher.Receivers {
+		if receiver.Name() == name {
+			return receiver
+		}
+	}
+
+	return nil
+}
+
+// retry delivers the pending entries of the outbox when their next attempt is due, until stop is closed
+func (dispatcher *Dispatcher) retry(outbox *Outbox, stop chan struct{}) {
+	ticker := time.NewTicker(outboxRetryTick)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			for _, entry := range outbox.Due() {
+				go dispatcher.deliver(outbox, entry)
+			}
+		}
+	}
+}
+
+// DeadLetters returns the number of pending notifications and the notifications which failed to deliver
+func DeadLetters() (int, []OutboxEntry) {
+	dispatcher.Mutex.RLock()
+	outbox := dispatcher.Outbox
+	dispatcher.Mutex.RUnlock()
+
+	if outbox == nil {
+		return 0, []OutboxEntry{}
+	}
+
+	return outbox.Snapshot()
+}
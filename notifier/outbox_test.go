@@ -0,0 +1,114 @@
+package notifier
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+)
+
+func TestOutboxRetryAndDeadLetters(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "outbox.json")
+
+	outbox, err := NewOutbox(path, OutboxConfig{MaxAttempts: 2, Backoff: "1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry := outbox.Add("webhook", []Event{{Status: StatusFiring, UUID: "a"}})
+	outbox.Fail(entry, errors.New("connection refused"))
+
+	assert.Equal(t, len(outbox.Due()), 0)
+	assert.Equal(t, entry.NextAttemptAt.After(time.Now()), true)
+
+	// The pending entry survives a restart
+	restored, err := NewOutbox(path, OutboxConfig{MaxAttempts: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(restored.Pending), 1)
+	assert.Equal(t, restored.Pending[0].Attempts, 1)
+
+	restored.Pending[0].NextAttemptAt = time.Now()
+	due := restored.Due()
+	assert.Equal(t, len(due), 1)
+
+	restored.Fail(due[0], errors.New("connection refused"))
+
+	pending, deadLetters := restored.Snapshot()
+	assert.Equal(t, pending, 0)
+	assert.Equal(t, len(deadLetters), 1)
+	assert.Equal(t, deadLetters[0].LastError, "connection refused")
+}
+
+func TestOutboxDeadLettersLimitAndCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "outbox.json")
+
+	outbox, err := NewOutbox(path, OutboxConfig{MaxAttempts: 1, MaxDeadLetters: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, uuid := range []string{"a", "b", "c"} {
+		entry := outbox.Add("webhook", []Event{{Status: StatusFiring, UUID: uuid}})
+		outbox.Fail(entry, errors.New("connection refused"))
+	}
+
+	_, deadLetters := outbox.Snapshot()
+	assert.Equal(t, len(deadLetters), 2)
+	assert.Equal(t, deadLetters[0].Events[0].UUID, "b")
+
+	// Only the outbox file is left after the atomic writes
+	files, _ := os.ReadDir(dir)
+	assert.Equal(t, len(files), 1)
+
+	if err = os.WriteFile(path, []byte(`{"pending": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := NewOutbox(path, OutboxConfig{})
+	assert.Equal(t, err, nil)
+	assert.Equal(t, len(restored.Pending), 0)
+
+	quarantined, _ := filepath.Glob(path + ".corrupt-*")
+	assert.Equal(t, len(quarantined), 1)
+}
+
+func TestOutboxSupersededEvents(t *testing.T) {
+	outbox, err := NewOutbox(filepath.Join(t.TempDir(), "outbox.json"), OutboxConfig{Backoff: "1s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firedAt := time.Now().UTC()
+	firing := outbox.Add("webhook", []Event{{Status: StatusFiring, UUID: "a", Timestamp: firedAt}, {Status: StatusFiring, UUID: "b", Timestamp: firedAt}})
+	outbox.Fail(firing, errors.New("connection refused"))
+
+	// The resolved event replaces the firing one waiting for the retry
+	resolved := outbox.Add("webhook", []Event{{Status: StatusResolved, UUID: "a", Timestamp: firedAt.Add(time.Second)}})
+	assert.Equal(t, len(firing.Events), 1)
+	assert.Equal(t, firing.Events[0].UUID, "b")
+
+	// The events of the other receivers are kept
+	other := outbox.Add("slack", []Event{{Status: StatusFiring, UUID: "a", Timestamp: firedAt}})
+	outbox.Fail(other, errors.New("connection refused"))
+	assert.Equal(t, len(other.Events), 1)
+	outbox.Done(other)
+
+	outbox.Done(resolved)
+	outbox.Done(firing)
+
+	// The firing event failed in flight is dropped after the resolved one is delivered
+	inFlight := outbox.Add("webhook", []Event{{Status: StatusFiring, UUID: "c", Timestamp: firedAt}})
+	outbox.Done(outbox.Add("webhook", []Event{{Status: StatusResolved, UUID: "c", Timestamp: firedAt.Add(time.Second)}}))
+	outbox.Fail(inFlight, errors.New("connection refused"))
+
+	pending, deadLetters := outbox.Snapshot()
+	assert.Equal(t, pending, 0)
+	assert.Equal(t, len(deadLetters), 0)
+}
@@ -0,0 +1,193 @@
+package notifier
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"text/template"
+)
+
+const pagerDutyEventsUrl = "https://events.pagerduty.com/v2/enqueue"
+
+var pagerDutySeverities = []string{"critical", "error", "warning", "info"}
+
+type PagerDutyConfig struct {
+	Url        *string           `json:"url"`
+	RoutingKey string            `json:"routing_key"`
+	Source     string            `json:"source"`
+	Severity   string            `json:"severity"`
+	Severities map[string]string `json:"severities"` // Severity by rule scope
+	Component  *string           `json:"component"`
+	Group      *string           `json:"group"`
+	Class      *string           `json:"class"`
+}
+
+// PagerDuty sends trigger and resolve events to the PagerDuty Events API v2.
+// The rule UUID is used as the dedup key, so both events refer to the same incident
+type PagerDuty struct {
+	name      string
+	config    PagerDutyConfig
+	url       string
+	component *template.Template
+	group     *template.Template
+	class     *template.Template
+}
+
+type PagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *PagerDutyPayload `json:"payload,omitempty"`
+}
+
+type PagerDutyPayload struct {
+	Summary       string                 `json:"summary"`
+	Source        string                 `json:"source"`
+	Severity      string                 `json:"severity"`
+	Timestamp     string                 `json:"timestamp"`
+	Component     string                 `json:"component,omitempty"`
+	Group         string                 `json:"group,omitempty"`
+	Class         string                 `json:"class,omitempty"`
+	CustomDetails map[string]interface{} `json:"custom_details"`
+}
+
+func NewPagerDuty(name string, config PagerDutyConfig) (*PagerDuty, error) {
+	if config.RoutingKey == "" {
+		return nil, errors.New("pagerduty routing key is required")
+	}
+
+	if config.Source == "" {
+		config.Source = "w-alerts"
+	}
+
+	if config.Severity == "" {
+		config.Severity = "critical"
+	}
+
+	for _, severity := range append([]string{config.Severity}, mapValues(config.Severities)...) {
+		if !slices.Contains(pagerDutySeverities, severity) {
+			return nil, fmt.Errorf("unsupported pagerduty severity '%s'", severity)
+		}
+	}
+
+	pagerDuty := &PagerDuty{name: name, config: config, url: pagerDutyEventsUrl}
+	if config.Url != nil {
+		pagerDuty.url = *config.Url
+	}
+
+	component := "{{ .ScopeName }}"
+	if config.Component != nil {
+		component = *config.Component
+	}
+
+	group := "{{ .File }}"
+	if config.Group != nil {
+		group = *config.Group
+	}
+
+	class := ""
+	if config.Class != nil {
+		class = *config.Class
+	}
+
+	var err error
+	if pagerDuty.component, err = template.New("component").Funcs(templateFuncs).Parse(component); err != nil {
+		return nil, fmt.Errorf("error parsing pagerduty component: %w", err)
+	}
+
+	if pagerDuty.group, err = template.New("group").Funcs(templateFuncs).Parse(group); err != nil {
+		return nil, fmt.Errorf("error parsing pagerduty group: %w", err)
+	}
+
+	if pagerDuty.class, err = template.New("class").Funcs(templateFuncs).Parse(class); err != nil {
+		return nil, fmt.Errorf("error parsing pagerduty class: %w", err)
+	}
+
+	return pagerDuty, nil
+}
+
+func (pagerDuty *PagerDuty) Name() string {
+	return pagerDuty.name
+}
+
+func (pagerDuty *PagerDuty) Notify(event Event) error {
+	pagerDutyEvent, err := pagerDuty.Event(event)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(pagerDutyEvent)
+	if err != nil {
+		return err
+	}
+
+	return postJSON("POST", pagerDuty.url, nil, payload)
+}
+
+func (pagerDuty *PagerDuty) Event(event Event) (*PagerDutyEvent, error) {
+	pagerDutyEvent := &PagerDutyEvent{
+		RoutingKey:  pagerDuty.config.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    event.UUID,
+	}
+
+	if !event.IsFiring() {
+		return pagerDutyEvent, nil
+	}
+
+	severity := pagerDuty.config.Severity
+	if value, ok := pagerDuty.config.Severities[event.ScopeName()]; ok {
+		severity = value
+	}
+
+	var component, group, class bytes.Buffer
+	if err := pagerDuty.component.Execute(&component, event); err != nil {
+		return nil, fmt.Errorf("error rendering pagerduty component: %w", err)
+	}
+
+	if err := pagerDuty.group.Execute(&group, event); err != nil {
+		return nil, fmt.Errorf("error rendering pagerduty group: %w", err)
+	}
+
+	if err := pagerDuty.class.Execute(&class, event); err != nil {
+		return nil, fmt.Errorf("error rendering pagerduty class: %w", err)
+	}
+
+	pagerDutyEvent.EventAction = "trigger"
+	pagerDutyEvent.Payload = &PagerDutyPayload{
+		Summary:   event.Title(),
+		Source:    pagerDuty.config.Source,
+		Severity:  severity,
+		Timestamp: event.Timestamp.UTC().Format("2006-01-02T15:04:05.000Z"),
+		Component: component.String(),
+		Group:     group.String(),
+		Class:     class.String(),
+		CustomDetails: map[string]interface{}{
+			"description":   event.Description,
+			"rules_results": event.RulesResults,
+			"file":          event.File,
+		},
+	}
+
+	return pagerDutyEvent, nil
+}
+
+// ScopeName returns the scope of the rule or an empty string
+func (event Event) ScopeName() string {
+	if event.Scope == nil {
+		return ""
+	}
+
+	return *event.Scope
+}
+
+func mapValues(values map[string]string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		result = append(result, value)
+	}
+
+	return result
+}
@@ -0,0 +1,71 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+)
+
+func TestPagerDutyNotify(t *testing.T) {
+	received := make([]PagerDutyEvent, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event PagerDutyEvent
+
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Error(err)
+		}
+
+		received = append(received, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	url := server.URL
+	pagerDuty, err := NewPagerDuty("pagerduty", PagerDutyConfig{
+		Url:        &url,
+		RoutingKey: "routing-key",
+		Severities: map[string]string{"api": "error"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := "api"
+	event := Event{
+		Status:    StatusFiring,
+		UUID:      "8240a321-7dd6-ea42-39f6-da1a7f5deca9",
+		Name:      "Error rate",
+		Scope:     &scope,
+		File:      "rules/es.json",
+		Timestamp: time.Now(),
+	}
+
+	if err = pagerDuty.Notify(event); err != nil {
+		t.Fatal(err)
+	}
+
+	event.Status = StatusResolved
+	if err = pagerDuty.Notify(event); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(received), 2)
+
+	assert.Equal(t, received[0].EventAction, "trigger")
+	assert.Equal(t, received[0].DedupKey, event.UUID)
+	assert.Equal(t, received[0].Payload.Summary, "[API] Error rate")
+	assert.Equal(t, received[0].Payload.Severity, "error")
+	assert.Equal(t, received[0].Payload.Component, "api")
+	assert.Equal(t, received[0].Payload.Group, "rules/es.json")
+
+	assert.Equal(t, received[1].EventAction, "resolve")
+	assert.Equal(t, received[1].DedupKey, event.UUID)
+	assert.Equal(t, received[1].Payload == nil, true)
+}
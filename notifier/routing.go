@@ -0,0 +1,149 @@
+package notifier
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+)
+
+var MatchFields = []string{"uuid", "name", "scope", "file", "severity"}
+
+// Route selects the receivers of an event. The children are checked in order and
+// the first matching one is used, unless it has "continue": true. If none of the
+// children match, the receivers of the route itself are used
+type Route struct {
+	Matchers
+	Receivers []string `json:"receivers"`
+	Continue  bool     `json:"continue"`
+	Routes    []*Route `json:"routes"`
+}
+
+// Matchers select the events by the equality of the fields (match)
+// or by regular expressions (match_re)
+type Matchers struct {
+	Match   map[string]string `json:"match"`
+	MatchRe map[string]string `json:"match_re"`
+
+	matchRe map[string]*regexp.Regexp
+}
+
+func LoadRoutes(path string) (*Route, error) {
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var route Route
+	err = json.Unmarshal(jsonBytes, &route)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling routes: %w", err)
+	}
+
+	return &route, nil
+}
+
+// Compile checks the fields and compiles the regular expressions
+func (matchers *Matchers) Compile() error {
+	for field := range matchers.Match {
+		if !slices.Contains(MatchFields, field) {
+			return fmt.Errorf("unsupported match field '%s'", field)
+		}
+	}
+
+	matchers.matchRe = make(map[string]*regexp.Regexp)
+	for field, pattern := range matchers.MatchRe {
+		if !slices.Contains(MatchFields, field) {
+			return fmt.Errorf("unsupported match_re field '%s'", field)
+		}
+
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return fmt.Errorf("invalid match_re for '%s': %w", field, err)
+		}
+
+		matchers.matchRe[field] = re
+	}
+
+	return nil
+}
+
+func (matchers *Matchers) Matches(event Event) bool {
+	fields := event.MatchFields()
+
+	for field, value := range matchers.Match {
+		if fields[field] != value {
+			return false
+		}
+	}
+
+	for field, re := range matchers.matchRe {
+		if !re.MatchString(fields[field]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Validate compiles the matchers and checks that the routes refer to the existing receivers
+func (route *Route) Validate(receivers []string) error {
+	if err := route.Compile(); err != nil {
+		return err
+	}
+
+	for _, receiver := range route.Receivers {
+		if !slices.Contains(receivers, receiver) {
+			return fmt.Errorf("unknown receiver '%s'", receiver)
+		}
+	}
+
+	for _, child := range route.Routes {
+		if err := child.Validate(receivers); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Select returns the names of the receivers for the event
+func (route *Route) Select(event Event) []string {
+	receivers := make([]string, 0)
+	matched := false
+
+	for _, child := range route.Routes {
+		if !child.Matches(event) {
+			continue
+		}
+
+		matched = true
+		for _, receiver := range child.Select(event) {
+			if !slices.Contains(receivers, receiver) {
+				receivers = append(receivers, receiver)
+			}
+		}
+
+		if !child.Continue {
+			break
+		}
+	}
+
+	if !matched {
+		return route.Receivers
+	}
+
+	return receivers
+}
+
+// MatchFields returns the values of the event which can be used in route matchers
+func (event Event) MatchFields() map[string]string {
+	return map[string]string{
+		"uuid":     event.UUID,
+		"name":     event.Name,
+		"scope":    event.ScopeName(),
+		"file":     event.File,
+		"severity": event.Severity,
+	}
+}
@@ -0,0 +1,51 @@
+package notifier
+
+import (
+	"testing"
+
+	"github.com/go-playground/assert"
+)
+
+func TestRouteSelect(t *testing.T) {
+	route := &Route{
+		Receivers: []string{"default"},
+		Routes: []*Route{
+			{
+				Matchers:  Matchers{Match: map[string]string{"scope": "api"}},
+				Receivers: []string{"api-team"},
+				Continue:  true,
+			},
+			{
+				Matchers:  Matchers{MatchRe: map[string]string{"severity": "critical|error"}},
+				Receivers: []string{"pagerduty"},
+				Routes: []*Route{
+					{Matchers: Matchers{Match: map[string]string{"file": "rules/billing.json"}}, Receivers: []string{"billing"}},
+				},
+			},
+			{
+				Matchers:  Matchers{Match: map[string]string{"scope": "api"}},
+				Receivers: []string{"unreachable"},
+			},
+		},
+	}
+
+	err := route.Validate([]string{"default", "api-team", "pagerduty", "billing", "unreachable"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	api := "api"
+	billing := "billing"
+
+	assert.Equal(t, route.Select(Event{Name: "Some rule"}), []string{"default"})
+	assert.Equal(t, route.Select(Event{Scope: &api}), []string{"api-team", "unreachable"})
+	assert.Equal(t, route.Select(Event{Scope: &api, Severity: "critical"}), []string{"api-team", "pagerduty"})
+	assert.Equal(t, route.Select(Event{Scope: &billing, Severity: "error", File: "rules/billing.json"}), []string{"billing"})
+	assert.Equal(t, route.Select(Event{Severity: "warning"}), []string{"default"})
+}
+
+func TestRouteValidate(t *testing.T) {
+	assert.NotEqual(t, (&Route{Receivers: []string{"missing"}}).Validate([]string{"default"}), nil)
+	assert.NotEqual(t, (&Route{Matchers: Matchers{Match: map[string]string{"unknown": "x"}}}).Validate(nil), nil)
+	assert.NotEqual(t, (&Route{Matchers: Matchers{MatchRe: map[string]string{"name": "("}}}).Validate(nil), nil)
+}
@@ -0,0 +1,185 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/wavix/w-alerts/utils"
+)
+
+// Silence mutes the notifications of the matching rules between StartsAt and EndsAt.
+// The silenced rules are still evaluated
+type Silence struct {
+	ID string `json:"id"`
+	Matchers
+	StartsAt  time.Time `json:"starts_at"`
+	EndsAt    time.Time `json:"ends_at"`
+	CreatedBy string    `json:"created_by"`
+	Comment   string    `json:"comment"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Silences struct {
+	Path     string
+	Silences []*Silence
+	Mutex    sync.RWMutex
+}
+
+var silences = &Silences{Silences: make([]*Silence, 0)}
+
+func (silence *Silence) IsActive(now time.Time) bool {
+	return !now.Before(silence.StartsAt) && now.Before(silence.EndsAt)
+}
+
+// LoadSilences reads the silences persisted in the file, the file is created on the first change
+func LoadSilences(path string) error {
+	silences.Mutex.Lock()
+	defer silences.Mutex.Unlock()
+
+	silences.Path = path
+
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return nil
+	}
+
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var loaded []*Silence
+	err = json.Unmarshal(jsonBytes, &loaded)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling silences: %w", err)
+	}
+
+	for _, silence := range loaded {
+		if err = silence.Compile(); err != nil {
+			return fmt.Errorf("silence %s: %w", silence.ID, err)
+		}
+	}
+
+	silences.Silences = loaded
+	silences.prune()
+	utils.Logger.Info().Msgf("Loaded %d silences from %v", len(silences.Silences), path)
+
+	return nil
+}
+
+// AddSilence validates and stores the new silence
+func AddSilence(silence Silence) (*Silence, error) {
+	if len(silence.Match) == 0 && len(silence.MatchRe) == 0 {
+		return nil, errors.New("at least one matcher is required")
+	}
+
+	if err := silence.Compile(); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return nil, errors.New("ends_at must be after starts_at")
+	}
+
+	id, err := uuid.NewV4()
+	if err != nil {
+		return nil, err
+	}
+
+	silence.ID = id.String()
+	silence.CreatedAt = now
+
+	silences.Mutex.Lock()
+	defer silences.Mutex.Unlock()
+
+	silences.Silences = append(silences.Silences, &silence)
+	silences.save()
+
+	return &silence, nil
+}
+
+// ExpireSilence ends the silence now, returns false if there is no such silence
+func ExpireSilence(id string) bool {
+	silences.Mutex.Lock()
+	defer silences.Mutex.Unlock()
+
+	now := time.Now().UTC()
+	for _, silence := range silences.Silences {
+		if silence.ID != id {
+			continue
+		}
+
+		if silence.EndsAt.After(now) {
+			silence.EndsAt = now
+			silences.save()
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func ListSilences() []Silence {
+	silences.Mutex.RLock()
+	defer silences.Mutex.RUnlock()
+
+	result := make([]Silence, 0, len(silences.Silences))
+	for _, silence := range silences.Silences {
+		result = append(result, *silence)
+	}
+
+	return result
+}
+
+// Silenced returns the active silence which matches the event
+func Silenced(event Event) *Silence {
+	silences.Mutex.RLock()
+	defer silences.Mutex.RUnlock()
+
+	now := time.Now()
+	for _, silence := range silences.Silences {
+		if silence.IsActive(now) && silence.Matches(event) {
+			return silence
+		}
+	}
+
+	return nil
+}
+
+// prune removes the expired silences
+func (silences *Silences) prune() {
+	now := time.Now()
+	silences.Silences = slices.DeleteFunc(silences.Silences, func(silence *Silence) bool {
+		return !now.Before(silence.EndsAt)
+	})
+}
+
+func (silences *Silences) save() {
+	silences.prune()
+
+	if silences.Path == "" {
+		return
+	}
+
+	jsonBytes, err := json.Marshal(silences.Silences)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error marshalling silences: %v", err)
+		return
+	}
+
+	err = os.WriteFile(silences.Path, jsonBytes, 0644)
+	if err != nil {
+		utils.Logger.Error().Msgf("Error writing silences: %v", err)
+	}
+}
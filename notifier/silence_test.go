@@ -0,0 +1,84 @@
+package notifier
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+)
+
+func TestSilences(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "silences.json")
+	if err := LoadSilences(path); err != nil {
+		t.Fatal(err)
+	}
+
+	scope := "api"
+	event := Event{UUID: "a", Name: "Error rate", Scope: &scope}
+
+	silence, err := AddSilence(Silence{
+		Matchers: Matchers{Match: map[string]string{"scope": "api"}, MatchRe: map[string]string{"name": "Error.*"}},
+		EndsAt:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, Silenced(event).ID, silence.ID)
+	assert.Equal(t, Silenced(Event{UUID: "b", Name: "Error rate"}) == nil, true)
+
+	// Silences are restored from the file
+	silences.Silences = nil
+	if err = LoadSilences(path); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, Silenced(event).ID, silence.ID)
+
+	assert.Equal(t, ExpireSilence(silence.ID), true)
+	assert.Equal(t, Silenced(event) == nil, true)
+
+	// The expired silences are removed
+	assert.Equal(t, len(ListSilences()), 0)
+	assert.Equal(t, ExpireSilence(silence.ID), false)
+
+	if err = os.WriteFile(path, []byte(`[{"id": "expired", "match": {"name": "Latency"}, "ends_at": "2024-01-01T00:00:00Z"}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = LoadSilences(path); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, len(ListSilences()), 0)
+
+	_, err = AddSilence(Silence{EndsAt: time.Now().Add(time.Hour)})
+	assert.NotEqual(t, err, nil)
+}
+
+func TestSilenceDeliversResolved(t *testing.T) {
+	if err := LoadSilences(filepath.Join(t.TempDir(), "silences.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	silence, err := AddSilence(Silence{
+		Matchers: Matchers{Match: map[string]string{"name": "Latency"}},
+		EndsAt:   time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ExpireSilence(silence.ID)
+
+	receiver := &recorder{}
+	dispatcher := &Dispatcher{Receivers: []Notifier{receiver}, firing: make(map[string]*firingState)}
+
+	dispatcher.Dispatch(Event{Status: StatusFiring, UUID: "a", Name: "Latency"})
+	assert.Equal(t, len(receiver.received()), 0)
+
+	dispatcher.Dispatch(Event{Status: StatusResolved, UUID: "a", Name: "Latency"})
+	assert.Equal(t, len(receiver.received()), 1)
+	assert.Equal(t, receiver.received()[0][0].Status, StatusResolved)
+}
@@ -0,0 +1,213 @@
+package notifier
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/wavix/w-alerts/utils"
+)
+
+const (
+	slackColorFiring   = "#dc3545"
+	slackColorResolved = "#28a745"
+
+	// Block Kit limits of the text length in characters
+	slackHeaderLimit  = 150
+	slackSectionLimit = 3000
+	slackTruncation   = "..."
+)
+
+type SlackConfig struct {
+	Url       string  `json:"url"`
+	Channel   *string `json:"channel"`
+	Username  *string `json:"username"`
+	IconEmoji *string `json:"icon_emoji"`
+}
+
+// Slack sends messages to Slack incoming webhooks (Block Kit)
+// or to Mattermost, which accepts the legacy attachments format
+type Slack struct {
+	name       string
+	config     SlackConfig
+	mattermost bool
+}
+
+type SlackMessage struct {
+	Text        string            `json:"text"`
+	Channel     *string           `json:"channel,omitempty"`
+	Username    *string           `json:"username,omitempty"`
+	IconEmoji   *string           `json:"icon_emoji,omitempty"`
+	Attachments []SlackAttachment `json:"attachments"`
+}
+
+type SlackAttachment struct {
+	Color    string        `json:"color"`
+	Fallback string        `json:"fallback"`
+	Title    string        `json:"title,omitempty"`
+	Text     string        `json:"text,omitempty"`
+	Fields   []SlackField  `json:"fields,omitempty"`
+	Footer   string        `json:"footer,omitempty"`
+	Ts       int64         `json:"ts,omitempty"`
+	Blocks   []interface{} `json:"blocks,omitempty"`
+}
+
+type SlackField struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+	Short bool   `json:"short"`
+}
+
+func NewSlack(name string, config SlackConfig) (*Slack, error) {
+	if config.Url == "" {
+		return nil, errors.New("slack url is required")
+	}
+
+	return &Slack{name: name, config: config}, nil
+}
+
+func NewMattermost(name string, config SlackConfig) (*Slack, error) {
+	if config.Url == "" {
+		return nil, errors.New("mattermost url is required")
+	}
+
+	return &Slack{name: name, config: config, mattermost: true}, nil
+}
+
+func (slack *Slack) Name() string {
+	return slack.name
+}
+
+func (slack *Slack) Notify(event Event) error {
+	payload, err := json.Marshal(slack.Message(event))
+	if err != nil {
+		return err
+	}
+
+	return postJSON("POST", slack.config.Url, nil, payload)
+}
+
+func (slack *Slack) NotifyGroup(events []Event) error {
+	payload, err := json.Marshal(slack.Digest(events))
+	if err != nil {
+		return err
+	}
+
+	return postJSON("POST", slack.config.Url, nil, payload)
+}
+
+func (slack *Slack) Message(event Event) SlackMessage {
+	attachment := slack.attachment(event)
+
+	return SlackMessage{
+		Text:        attachment.Fallback,
+		Channel:     slack.config.Channel,
+		Username:    slack.config.Username,
+		IconEmoji:   slack.config.IconEmoji,
+		Attachments: []SlackAttachment{attachment},
+	}
+}
+
+// Digest builds a single message with an attachment per event
+func (slack *Slack) Digest(events []Event) SlackMessage {
+	attachments := make([]SlackAttachment, 0, len(events))
+	for _, event := range events {
+		attachments = append(attachments, slack.attachment(event))
+	}
+
+	return SlackMessage{
+		Text:        DigestSummary(events),
+		Channel:     slack.config.Channel,
+		Username:    slack.config.Username,
+		IconEmoji:   slack.config.IconEmoji,
+		Attachments: attachments,
+	}
+}
+
+func (slack *Slack) attachment(event Event) SlackAttachment {
+	title := event.Title()
+	state := "Firing"
+	color := slackColorFiring
+
+	if !event.IsFiring() {
+		state = "Resolved"
+		color = slackColorResolved
+	}
+
+	headline := fmt.Sprintf("[%s] %s", strings.ToUpper(state), title)
+	attachment := SlackAttachment{
+		Color:    color,
+		Fallback: headline,
+		Footer:   event.File,
+		Ts:       event.Timestamp.Unix(),
+	}
+
+	if slack.mattermost {
+		attachment.Title = headline
+		attachment.Text = event.Description
+		attachment.Fields = []SlackField{
+			{Title: "Status", Value: state, Short: true},
+			{Title: "Values", Value: formatResults(event.RulesResults), Short: true},
+		}
+
+		return attachment
+	}
+
+	attachment.Blocks = []interface{}{
+		map[string]interface{}{
+			"type": "header",
+			"text": map[string]interface{}{"type": "plain_text", "text": truncateSlack(headline, slackHeaderLimit)},
+		},
+	}
+
+	// Slack rejects the section with an empty text
+	if event.Description != "" {
+		attachment.Blocks = append(attachment.Blocks, map[string]interface{}{
+			"type": "section",
+			"text": map[string]interface{}{"type": "mrkdwn", "text": truncateSlack(event.Description, slackSectionLimit)},
+		})
+	}
+
+	attachment.Blocks = append(attachment.Blocks, map[string]interface{}{
+		"type": "context",
+		"elements": []interface{}{
+			map[string]interface{}{"type": "mrkdwn", "text": fmt.Sprintf("*Values:* %s", formatResults(event.RulesResults))},
+		},
+	})
+
+	return attachment
+}
+
+// truncateSlack cuts the text to the limit of characters
+func truncateSlack(text string, limit int) string {
+	runes := []rune(text)
+	if len(runes) <= limit {
+		return text
+	}
+
+	return string(runes[:limit-len(slackTruncation)]) + slackTruncation
+}
+
+// Title returns the rule name with the scope prefix, as shown on the status page
+func (event Event) Title() string {
+	title := utils.ScopedName(event.Name, event.Scope)
+	if event.Flapping {
+		title += " is flapping"
+	}
+
+	return title
+}
+
+func formatResults(results []interface{}) string {
+	if len(results) == 0 {
+		return "-"
+	}
+
+	values := make([]string, 0, len(results))
+	for _, value := range results {
+		values = append(values, fmt.Sprintf("%v", value))
+	}
+
+	return strings.Join(values, ", ")
+}
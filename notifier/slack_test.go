@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/go-playground/assert"
+)
+
+func TestSlackNotify(t *testing.T) {
+	var received SlackMessage
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &received); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer server.Close()
+
+	slack, err := NewSlack("slack", SlackConfig{Url: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := "api"
+	event := Event{
+		Status:       StatusFiring,
+		Name:         "Error rate",
+		Scope:        &scope,
+		Description:  "Count: 10, error rate: 0.5",
+		RulesResults: []interface{}{10, 0.5},
+		Timestamp:    time.Now(),
+	}
+
+	err = slack.Notify(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, received.Text, "[FIRING] [API] Error rate")
+	assert.Equal(t, received.Attachments[0].Color, slackColorFiring)
+	assert.Equal(t, len(received.Attachments[0].Blocks), 3)
+
+	event.Status = StatusResolved
+	err = slack.Notify(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, received.Text, "[RESOLVED] [API] Error rate")
+	assert.Equal(t, received.Attachments[0].Color, slackColorResolved)
+
+	// The empty description has no section, the long texts are truncated
+	event.Description = ""
+	event.Name = strings.Repeat("ошибка ", 30)
+	blocks := slack.attachment(event).Blocks
+	assert.Equal(t, len(blocks), 2)
+
+	header := blocks[0].(map[string]interface{})["text"].(map[string]interface{})["text"].(string)
+	assert.Equal(t, utf8.RuneCountInString(header), slackHeaderLimit)
+	assert.Equal(t, strings.HasSuffix(header, slackTruncation), true)
+
+	event.Description = strings.Repeat("a", 5000)
+	section := slack.attachment(event).Blocks[1].(map[string]interface{})["text"].(map[string]interface{})["text"].(string)
+	assert.Equal(t, len(section), slackSectionLimit)
+}
+
+func TestMattermostMessage(t *testing.T) {
+	mattermost, err := NewMattermost("mattermost", SlackConfig{Url: "http://localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	message := mattermost.Message(Event{
+		Status:       StatusFiring,
+		Name:         "Error rate",
+		Description:  "Count: 10",
+		RulesResults: []interface{}{10},
+	})
+
+	assert.Equal(t, message.Attachments[0].Title, "[FIRING] Error rate")
+	assert.Equal(t, message.Attachments[0].Text, "Count: 10")
+	assert.Equal(t, message.Attachments[0].Fields[1].Value, "10")
+}
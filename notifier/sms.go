@@ -0,0 +1,262 @@
+package notifier
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+const OptInSMS = "sms"
+
+const (
+	smsGSMSegment           = 160
+	smsGSMConcatSegment     = 153
+	smsUnicodeSegment       = 70
+	smsUnicodeConcatSegment = 67
+	smsGSMAlphabet          = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	smsGSMExtension         = "^{}\\[~]|€" // Sent with the escape character, each takes 2 septets
+	smsTruncation           = "..."
+)
+
+const (
+	defaultSMSBodyTemplate = `{"from": {{ json .From }}, "to": {{ json .To }}, "text": {{ json .Text }}}`
+	defaultSMSTextTemplate = `[{{ .Event.Status | upper }}] {{ .Event.Title }}: {{ .Event.Description }}`
+)
+
+type SMSConfig struct {
+	Url          string            `json:"url"`
+	Method       *string           `json:"method"`
+	Headers      map[string]string `json:"headers"`
+	From         string            `json:"from"`
+	To           []string          `json:"to"`
+	BodyTemplate *string           `json:"body_template"`
+	TextTemplate *string           `json:"text_template"`
+	MaxSegments  int               `json:"max_segments"`
+}
+
+// SMS sends text messages through an HTTP SMS gateway. Only the rules
+// which opted in with "opt_in": ["sms"] are sent
+type SMS struct {
+	name   string
+	config SMSConfig
+	url    *template.Template
+	body   *template.Template
+	text   *template.Template
+}
+
+type SMSMessage struct {
+	From  string
+	To    string
+	Text  string
+	Event Event
+}
+
+func NewSMS(name string, config SMSConfig) (*SMS, error) {
+	if config.Url == "" {
+		return nil, errors.New("sms url is required")
+	}
+
+	if len(config.To) == 0 {
+		return nil, errors.New("sms recipients are required")
+	}
+
+	if config.MaxSegments <= 0 {
+		config.MaxSegments = 1
+	}
+
+	bodyTemplate := defaultSMSBodyTemplate
+	if config.BodyTemplate != nil {
+		bodyTemplate = *config.BodyTemplate
+	}
+
+	textTemplate := defaultSMSTextTemplate
+	if config.TextTemplate != nil {
+		textTemplate = *config.TextTemplate
+	}
+
+	funcs := template.FuncMap{"upper": strings.ToUpper}
+	for key, fn := range templateFuncs {
+		funcs[key] = fn
+	}
+
+	sms := &SMS{name: name, config: config}
+
+	var err error
+	if sms.url, err = template.New("url").Funcs(funcs).Parse(config.Url); err != nil {
+		return nil, fmt.Errorf("error parsing sms url template: %w", err)
+	}
+
+	if sms.body, err = template.New("body").Funcs(funcs).Parse(bodyTemplate); err != nil {
+		return nil, fmt.Errorf("error parsing sms body template: %w", err)
+	}
+
+	if sms.text, err = template.New("text").Funcs(funcs).Parse(textTemplate); err != nil {
+		return nil, fmt.Errorf("error parsing sms text template: %w", err)
+	}
+
+	return sms, nil
+}
+
+func (sms *SMS) Name() string {
+	return sms.name
+}
+
+func (sms *SMS) Notify(event Event) error {
+	if !event.HasOptIn(OptInSMS) {
+		return nil
+	}
+
+	var text bytes.Buffer
+	if err := sms.text.Execute(&text, SMSMessage{From: sms.config.From, Event: event}); err != nil {
+		return fmt.Errorf("error rendering sms text: %w", err)
+	}
+
+	return sms.send(text.String(), event)
+}
+
+// NotifyGroup sends a single SMS with the summary of the opted in events
+func (sms *SMS) NotifyGroup(events []Event) error {
+	optedIn := make([]Event, 0, len(events))
+	for _, event := range events {
+		if event.HasOptIn(OptInSMS) {
+			optedIn = append(optedIn, event)
+		}
+	}
+
+	if len(optedIn) == 0 {
+		return nil
+	}
+
+	if len(optedIn) == 1 {
+		return sms.Notify(optedIn[0])
+	}
+
+	firing := make([]string, 0)
+	resolved := make([]string, 0)
+	for _, event := range optedIn {
+		if event.IsFiring() {
+			firing = append(firing, event.Title())
+		} else {
+			resolved = append(resolved, event.Title())
+		}
+	}
+
+	text := fmt.Sprintf("[ALERTS] %s.", DigestSummary(optedIn))
+	if len(firing) > 0 {
+		text += fmt.Sprintf(" FIRING: %s.", strings.Join(firing, "; "))
+	}
+
+	if len(resolved) > 0 {
+		text += fmt.Sprintf(" RESOLVED: %s.", strings.Join(resolved, "; "))
+	}
+
+	return sms.send(text, optedIn[0])
+}
+
+func (sms *SMS) send(text string, event Event) error {
+	message := SMSMessage{
+		From:  sms.config.From,
+		Text:  TruncateSMS(text, sms.config.MaxSegments),
+		Event: event,
+	}
+
+	method := "POST"
+	if sms.config.Method != nil {
+		method = strings.ToUpper(*sms.config.Method)
+	}
+
+	errs := make([]error, 0)
+	for _, recipient := range ResolveRecipients(sms.config.To, ContactPhone) {
+		message.To = recipient
+
+		var url, body bytes.Buffer
+		if err := sms.url.Execute(&url, message); err != nil {
+			return fmt.Errorf("error rendering sms url: %w", err)
+		}
+
+		if err := sms.body.Execute(&body, message); err != nil {
+			return fmt.Errorf("error rendering sms body: %w", err)
+		}
+
+		err := postJSON(method, url.String(), sms.config.Headers, body.Bytes())
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", recipient, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// HasOptIn reports whether the rule enabled the channel which is sent only on demand (ex: sms)
+func (event Event) HasOptIn(channel string) bool {
+	return slices.Contains(event.OptIn, channel)
+}
+
+// TruncateSMS shortens the text so it fits into the given number of SMS segments.
+// GSM-7 texts fit 160 septets in a single segment and 153 per segment when
+// concatenated, the characters of the extension table take 2 septets.
+// Texts with other characters are sent in UCS-2 (70 and 67)
+func TruncateSMS(text string, maxSegments int) string {
+	isGSM := true
+	for _, char := range text {
+		if !strings.ContainsRune(smsGSMAlphabet, char) && !strings.ContainsRune(smsGSMExtension, char) {
+			isGSM = false
+			break
+		}
+	}
+
+	single, concat := smsGSMSegment, smsGSMConcatSegment
+	if !isGSM {
+		single, concat = smsUnicodeSegment, smsUnicodeConcatSegment
+	}
+
+	limit := single
+	if maxSegments > 1 {
+		limit = concat * maxSegments
+	}
+
+	if smsLength(text, isGSM) <= limit {
+		return text
+	}
+
+	limit -= len(smsTruncation)
+
+	length := 0
+	for index, char := range text {
+		length += smsCharLength(char, isGSM)
+		if length > limit {
+			return strings.TrimSpace(text[:index]) + smsTruncation
+		}
+	}
+
+	return text
+}
+
+// smsLength returns the length of the text in septets (GSM-7) or in UTF-16 code units (UCS-2)
+func smsLength(text string, isGSM bool) int {
+	length := 0
+	for _, char := range text {
+		length += smsCharLength(char, isGSM)
+	}
+
+	return length
+}
+
+func smsCharLength(char rune, isGSM bool) int {
+	if !isGSM {
+		if char > 0xFFFF {
+			return 2 // Surrogate pair
+		}
+
+		return 1
+	}
+
+	if strings.ContainsRune(smsGSMExtension, char) {
+		return 2
+	}
+
+	return 1
+}
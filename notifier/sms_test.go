@@ -0,0 +1,75 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/go-playground/assert"
+)
+
+func TestTruncateSMS(t *testing.T) {
+	text := strings.Repeat("a", 160)
+	assert.Equal(t, TruncateSMS(text, 1), text)
+
+	truncated := TruncateSMS(text+"a", 1)
+	assert.Equal(t, len(truncated), 160)
+	assert.Equal(t, strings.HasSuffix(truncated, "..."), true)
+
+	// The characters of the extension table take 2 septets
+	brackets := strings.Repeat("[", 80)
+	assert.Equal(t, TruncateSMS(brackets, 1), brackets)
+	assert.Equal(t, TruncateSMS(brackets+"]", 1), strings.Repeat("[", 78)+"...")
+	assert.Equal(t, TruncateSMS(strings.Repeat("€", 81), 1), strings.Repeat("€", 78)+"...")
+
+	// The text with a non GSM character is sent in UCS-2
+	unicode := strings.Repeat("ы", 71)
+	assert.Equal(t, utf8.RuneCountInString(TruncateSMS(unicode, 1)), 70)
+	assert.Equal(t, TruncateSMS(unicode[:140], 1), unicode[:140])
+
+	// Concatenated segments
+	long := strings.Repeat("a", 306)
+	assert.Equal(t, TruncateSMS(long, 2), long)
+	assert.Equal(t, len(TruncateSMS(long+"a", 2)), 306)
+}
+
+func TestSMSNotify(t *testing.T) {
+	received := make([]map[string]string, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		var message map[string]string
+		if err := json.Unmarshal(body, &message); err != nil {
+			t.Error(err)
+		}
+
+		received = append(received, message)
+	}))
+	defer server.Close()
+
+	sms, err := NewSMS("sms", SMSConfig{Url: server.URL, From: "alerts", To: []string{"+100"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := Event{Status: StatusFiring, UUID: "a", Name: "Error rate", Description: strings.Repeat("x", 200)}
+
+	// The rule didn't opt in
+	assert.Equal(t, sms.Notify(event), nil)
+	assert.Equal(t, len(received), 0)
+
+	event.OptIn = []string{OptInSMS}
+	assert.Equal(t, sms.Notify(event), nil)
+	assert.Equal(t, len(received), 1)
+	assert.Equal(t, received[0]["to"], "+100")
+	assert.Equal(t, received[0]["from"], "alerts")
+	assert.Equal(t, strings.HasPrefix(received[0]["text"], "[FIRING] Error rate: xxx"), true)
+
+	// "[" and "]" of the default template take 2 septets each
+	assert.Equal(t, len(received[0]["text"]), 158)
+}
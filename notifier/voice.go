@@ -0,0 +1,293 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/wavix/w-alerts/utils"
+)
+
+const OptInVoice = "voice"
+
+const (
+	defaultVoiceBodyTemplate = `{"from": {{ json .From }}, "to": {{ json .To }}, "text": {{ json .Text }}, "callback_url": {{ json .CallbackUrl }}}`
+	defaultVoiceTextTemplate = `Alert. {{ .Event.Title }}. {{ .Event.Description }}. Press {{ .AckDigit }} to acknowledge.`
+)
+
+type VoiceConfig struct {
+	Url           string            `json:"url"`
+	Method        *string           `json:"method"`
+	Headers       map[string]string `json:"headers"`
+	From          string            `json:"from"`
+	To            []string          `json:"to"`
+	CallbackUrl   string            `json:"callback_url"`
+	CallbackToken string            `json:"callback_token"` // Random by default, changes on restart
+	AckDigit      string            `json:"ack_digit"`
+	Delay         string            `json:"delay"`
+	RetryInterval string            `json:"retry_interval"`
+	Retries       int               `json:"retries"`
+	BodyTemplate  *string           `json:"body_template"`
+	TextTemplate  *string           `json:"text_template"`
+}
+
+// Voice places text-to-speech calls through an HTTP voice API. The numbers from
+// "to" are called one by one until someone acknowledges the alert by pressing
+// the ack digit or the rule is resolved. Only the rules which opted in with
+// "opt_in": ["voice"] are called. The callback url has the token which is
+// checked when the keypress is reported
+type Voice struct {
+	name          string
+	config        VoiceConfig
+	delay         time.Duration
+	retryInterval time.Duration
+	url           *template.Template
+	body          *template.Template
+	text          *template.Template
+
+	escalations map[string]chan struct{}
+	mutex       sync.Mutex
+}
+
+type VoiceCall struct {
+	From        string
+	To          string
+	Text        string
+	AckDigit    string
+	CallbackUrl string
+	Event       Event
+}
+
+func NewVoice(name string, config VoiceConfig) (*Voice, error) {
+	if config.Url == "" {
+		return nil, errors.New("voice url is required")
+	}
+
+	if len(config.To) == 0 {
+		return nil, errors.New("voice recipients are required")
+	}
+
+	if config.AckDigit == "" {
+		config.AckDigit = "1"
+	}
+
+	if config.CallbackToken == "" {
+		token, err := uuid.NewV4()
+		if err != nil {
+			return nil, fmt.Errorf("error generating voice callback token: %w", err)
+		}
+
+		config.CallbackToken = token.String()
+	}
+
+	voice := &Voice{
+		name:          name,
+		config:        config,
+		retryInterval: 5 * time.Minute,
+		escalations:   make(map[string]chan struct{}),
+	}
+
+	var err error
+	if config.Delay != "" {
+		if voice.delay, err = time.ParseDuration(config.Delay); err != nil {
+			return nil, fmt.Errorf("error parsing voice delay: %w", err)
+		}
+	}
+
+	if config.RetryInterval != "" {
+		if voice.retryInterval, err = time.ParseDuration(config.RetryInterval); err != nil {
+			return nil, fmt.Errorf("error parsing voice retry interval: %w", err)
+		}
+	}
+
+	bodyTemplate := defaultVoiceBodyTemplate
+	if config.BodyTemplate != nil {
+		bodyTemplate = *config.BodyTemplate
+	}
+
+	textTemplate := defaultVoiceTextTemplate
+	if config.TextTemplate != nil {
+		textTemplate = *config.TextTemplate
+	}
+
+	if voice.url, err = template.New("url").Funcs(templateFuncs).Parse(config.Url); err != nil {
+		return nil, fmt.Errorf("error parsing voice url template: %w", err)
+	}
+
+	if voice.body, err = template.New("body").Funcs(templateFuncs).Parse(bodyTemplate); err != nil {
+		return nil, fmt.Errorf("error parsing voice body template: %w", err)
+	}
+
+	if voice.text, err = template.New("text").Funcs(templateFuncs).Parse(textTemplate); err != nil {
+		return nil, fmt.Errorf("error parsing voice text template: %w", err)
+	}
+
+	return voice, nil
+}
+
+func (voice *Voice) Name() string {
+	return voice.name
+}
+
+func (voice *Voice) Notify(event Event) error {
+	if !event.HasOptIn(OptInVoice) {
+		return nil
+	}
+
+	if !event.IsFiring() {
+		voice.Stop(event.UUID)
+		return nil
+	}
+
+	if event.Ack.IsActive() {
+		return nil
+	}
+
+	voice.mutex.Lock()
+	defer voice.mutex.Unlock()
+
+	if _, exists := voice.escalations[event.UUID]; exists {
+		return nil
+	}
+
+	stop := make(chan struct{})
+	voice.escalations[event.UUID] = stop
+
+	go voice.escalate(event, stop)
+
+	return nil
+}
+
+// Stop cancels the calls for the rule, returns false if there were no active calls
+func (voice *Voice) Stop(uuid string) bool {
+	voice.mutex.Lock()
+	defer voice.mutex.Unlock()
+
+	stop, exists := voice.escalations[uuid]
+	if !exists {
+		return false
+	}
+
+	close(stop)
+	delete(voice.escalations, uuid)
+
+	return true
+}
+
+// Acknowledge stops the calls for the rule if the token is valid and the pressed digits match the ack digit
+func (voice *Voice) Acknowledge(uuid string, digits string, token string) bool {
+	if subtle.ConstantTimeCompare([]byte(token), []byte(voice.config.CallbackToken)) != 1 {
+		return false
+	}
+
+	if strings.TrimSpace(digits) != voice.config.AckDigit {
+		return false
+	}
+
+	return voice.Stop(uuid)
+}
+
+func (voice *Voice) escalate(event Event, stop chan struct{}) {
+	log := utils.Logger.Context(voice.name)
+
+	if !wait(voice.delay, stop) {
+		return
+	}
+
+	for round := 0; round <= voice.config.Retries; round++ {
+		for _, number := range ResolveRecipients(voice.config.To, ContactPhone) {
+			err := voice.call(event, number)
+			if err != nil {
+				log.Error().Msgf("Error calling %s for '%s': %v", number, event.Name, err)
+			} else {
+				log.Info().Msgf("Calling %s for '%s'", number, event.Name)
+			}
+
+			if !wait(voice.retryInterval, stop) {
+				return
+			}
+		}
+	}
+
+	log.Warn().Msgf("Nobody acknowledged the alert '%s'", event.Name)
+
+	voice.mutex.Lock()
+	delete(voice.escalations, event.UUID)
+	voice.mutex.Unlock()
+}
+
+func (voice *Voice) call(event Event, number string) error {
+	call := VoiceCall{
+		From:     voice.config.From,
+		To:       number,
+		AckDigit: voice.config.AckDigit,
+		Event:    event,
+	}
+
+	if voice.config.CallbackUrl != "" {
+		query := url.Values{"uuid": {event.UUID}, "receiver": {voice.name}, "token": {voice.config.CallbackToken}}
+		call.CallbackUrl = fmt.Sprintf("%s?%s", voice.config.CallbackUrl, query.Encode())
+	}
+
+	var text, requestUrl, body bytes.Buffer
+	if err := voice.text.Execute(&text, call); err != nil {
+		return fmt.Errorf("error rendering voice text: %w", err)
+	}
+	call.Text = text.String()
+
+	if err := voice.url.Execute(&requestUrl, call); err != nil {
+		return fmt.Errorf("error rendering voice url: %w", err)
+	}
+
+	if err := voice.body.Execute(&body, call); err != nil {
+		return fmt.Errorf("error rendering voice body: %w", err)
+	}
+
+	method := "POST"
+	if voice.config.Method != nil {
+		method = strings.ToUpper(*voice.config.Method)
+	}
+
+	return postJSON(method, requestUrl.String(), voice.config.Headers, body.Bytes())
+}
+
+// AcknowledgeCall passes the DTMF keypress to the voice receivers,
+// returns true if the escalation of the rule has been stopped
+func AcknowledgeCall(uuid string, receiver string, digits string, token string) bool {
+	dispatcher.Mutex.RLock()
+	defer dispatcher.Mutex.RUnlock()
+
+	acknowledged := false
+	for _, notifier := range expandReceivers(dispatcher.Receivers) {
+		voice, ok := notifier.(*Voice)
+		if !ok || (receiver != "" && voice.Name() != receiver) {
+			continue
+		}
+
+		if voice.Acknowledge(uuid, digits, token) {
+			acknowledged = true
+		}
+	}
+
+	return acknowledged
+}
+
+// wait returns false if the stop channel was closed before the duration elapsed
+func wait(duration time.Duration, stop chan struct{}) bool {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-stop:
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,162 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+)
+
+type voiceGateway struct {
+	server *httptest.Server
+	calls  []map[string]string
+	mutex  sync.Mutex
+}
+
+func newVoiceGateway(t *testing.T) *voiceGateway {
+	gateway := &voiceGateway{}
+	gateway.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		var call map[string]string
+		if err := json.Unmarshal(body, &call); err != nil {
+			t.Error(err)
+		}
+
+		gateway.mutex.Lock()
+		gateway.calls = append(gateway.calls, call)
+		gateway.mutex.Unlock()
+	}))
+
+	return gateway
+}
+
+func (gateway *voiceGateway) call(index int) map[string]string {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	return gateway.calls[index]
+}
+
+func (gateway *voiceGateway) numbers() []string {
+	gateway.mutex.Lock()
+	defer gateway.mutex.Unlock()
+
+	numbers := make([]string, 0, len(gateway.calls))
+	for _, call := range gateway.calls {
+		numbers = append(numbers, call["to"])
+	}
+
+	return numbers
+}
+
+func newTestVoice(t *testing.T, gateway *voiceGateway) *Voice {
+	voice, err := NewVoice("voice", VoiceConfig{
+		Url:           gateway.server.URL,
+		From:          "+100",
+		To:            []string{"+101", "+102"},
+		CallbackUrl:   "https://alerts.example.com/api/voice/callback",
+		CallbackToken: "secret",
+		RetryInterval: "30ms",
+		Retries:       1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return voice
+}
+
+func (voice *Voice) escalating(uuid string) bool {
+	voice.mutex.Lock()
+	defer voice.mutex.Unlock()
+
+	_, exists := voice.escalations[uuid]
+	return exists
+}
+
+func TestVoiceCallsInOrder(t *testing.T) {
+	gateway := newVoiceGateway(t)
+	defer gateway.server.Close()
+
+	voice := newTestVoice(t, gateway)
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "Error rate", OptIn: []string{OptInVoice}}
+
+	// The rule without the opt in is not called
+	assert.Equal(t, voice.Notify(Event{Status: StatusFiring, UUID: "b", Name: "Latency"}), nil)
+
+	assert.Equal(t, voice.Notify(firing), nil)
+
+	// The escalation ends after the last retry
+	deadline := time.Now().Add(time.Second)
+	for voice.escalating("a") && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, gateway.numbers(), []string{"+101", "+102", "+101", "+102"})
+	assert.Equal(t, voice.Stop("a"), false)
+}
+
+func TestVoiceStopOnResolve(t *testing.T) {
+	gateway := newVoiceGateway(t)
+	defer gateway.server.Close()
+
+	voice := newTestVoice(t, gateway)
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "Error rate", OptIn: []string{OptInVoice}}
+
+	assert.Equal(t, voice.Notify(firing), nil)
+	time.Sleep(10 * time.Millisecond)
+
+	resolved := firing
+	resolved.Status = StatusResolved
+	assert.Equal(t, voice.Notify(resolved), nil)
+
+	time.Sleep(80 * time.Millisecond)
+	assert.Equal(t, gateway.numbers(), []string{"+101"})
+}
+
+func TestVoiceAcknowledgeByDigit(t *testing.T) {
+	gateway := newVoiceGateway(t)
+	defer gateway.server.Close()
+
+	voice := newTestVoice(t, gateway)
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "Error rate", OptIn: []string{OptInVoice}}
+
+	dispatcher.Mutex.Lock()
+	receivers := dispatcher.Receivers
+	dispatcher.Receivers = []Notifier{voice}
+	dispatcher.Mutex.Unlock()
+
+	defer func() {
+		dispatcher.Mutex.Lock()
+		dispatcher.Receivers = receivers
+		dispatcher.Mutex.Unlock()
+	}()
+
+	assert.Equal(t, voice.Notify(firing), nil)
+	time.Sleep(10 * time.Millisecond)
+
+	// The callback url has the rule, the receiver and the token
+	callback, err := url.Parse(gateway.call(0)["callback_url"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := callback.Query()
+	assert.Equal(t, query.Get("uuid"), "a")
+	assert.Equal(t, query.Get("receiver"), "voice")
+	assert.Equal(t, query.Get("token"), "secret")
+
+	assert.Equal(t, AcknowledgeCall("a", "voice", "1", "invalid"), false)
+	assert.Equal(t, AcknowledgeCall("a", "voice", "2", "secret"), false)
+	assert.Equal(t, AcknowledgeCall("a", "voice", "1", "secret"), true)
+
+	time.Sleep(80 * time.Millisecond)
+	assert.Equal(t, gateway.numbers(), []string{"+101"})
+}
@@ -0,0 +1,194 @@
+package notifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/wavix/w-alerts/utils"
+)
+
+type WebhookConfig struct {
+	Url           string            `json:"url"`
+	Method        *string           `json:"method"`
+	Headers       map[string]string `json:"headers"`
+	Template      *string           `json:"template"`
+	GroupTemplate *string           `json:"group_template"`
+	// Skips the verification of the TLS certificate, only for the self-signed internal endpoints
+	InsecureSkipVerify bool `json:"insecure_skip_verify"`
+}
+
+type Webhook struct {
+	name          string
+	config        WebhookConfig
+	client        *http.Client
+	template      *template.Template
+	groupTemplate *template.Template
+}
+
+type WebhookGroup struct {
+	Events []Event `json:"events"`
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+var insecureHttpClient = &http.Client{
+	Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	Timeout:   10 * time.Second,
+}
+
+var templateFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+func init() {
+	for key, fn := range utils.TemplateFuncs {
+		templateFuncs[key] = fn
+		emailFuncs[key] = fn
+	}
+}
+
+func NewWebhook(name string, config WebhookConfig) (*Webhook, error) {
+	if config.Url == "" {
+		return nil, errors.New("webhook url is required")
+	}
+
+	webhook := &Webhook{name: name, config: config, client: httpClient}
+	if config.InsecureSkipVerify {
+		webhook.client = insecureHttpClient
+	}
+
+	if config.Template != nil {
+		tmpl, err := template.New(name).Funcs(templateFuncs).Parse(*config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing webhook template: %w", err)
+		}
+
+		webhook.template = tmpl
+	}
+
+	if config.GroupTemplate != nil {
+		tmpl, err := template.New(name).Funcs(templateFuncs).Parse(*config.GroupTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing webhook group template: %w", err)
+		}
+
+		webhook.groupTemplate = tmpl
+	}
+
+	return webhook, nil
+}
+
+func (webhook *Webhook) Name() string {
+	return webhook.name
+}
+
+func (webhook *Webhook) Notify(event Event) error {
+	payload, err := webhook.payload(event)
+	if err != nil {
+		return err
+	}
+
+	return webhook.post(payload)
+}
+
+// NotifyGroup sends the digest as {"events": [...]} or renders it with group_template.
+// If only the per-event template is configured, the events are sent one by one
+func (webhook *Webhook) NotifyGroup(events []Event) error {
+	group := WebhookGroup{Events: events}
+
+	if webhook.groupTemplate == nil && webhook.template != nil {
+		errs := make([]error, 0)
+		for _, event := range events {
+			if err := webhook.Notify(event); err != nil {
+				errs = append(errs, err)
+			}
+		}
+
+		return errors.Join(errs...)
+	}
+
+	var payload []byte
+	var err error
+
+	if webhook.groupTemplate != nil {
+		var buffer bytes.Buffer
+		err = webhook.groupTemplate.Execute(&buffer, group)
+		payload = buffer.Bytes()
+	} else {
+		payload, err = json.Marshal(group)
+	}
+
+	if err != nil {
+		return fmt.Errorf("error rendering webhook digest: %w", err)
+	}
+
+	return webhook.post(payload)
+}
+
+func (webhook *Webhook) post(payload []byte) error {
+	method := "POST"
+	if webhook.config.Method != nil {
+		method = strings.ToUpper(*webhook.config.Method)
+	}
+
+	return sendJSON(webhook.client, method, webhook.config.Url, webhook.config.Headers, payload)
+}
+
+func (webhook *Webhook) payload(event Event) ([]byte, error) {
+	if webhook.template == nil {
+		return json.Marshal(event)
+	}
+
+	var buffer bytes.Buffer
+	err := webhook.template.Execute(&buffer, event)
+	if err != nil {
+		return nil, fmt.Errorf("error rendering webhook template: %w", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func postJSON(method string, url string, headers map[string]string, payload []byte) error {
+	return sendJSON(httpClient, method, url, headers, payload)
+}
+
+func sendJSON(client *http.Client, method string, url string, headers map[string]string, payload []byte) error {
+	req, err := http.NewRequest(method, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close() // nolint:errcheck
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected response status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
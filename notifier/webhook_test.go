@@ -0,0 +1,110 @@
+package notifier
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-playground/assert"
+)
+
+type webhookRequest struct {
+	method  string
+	headers http.Header
+	body    []byte
+}
+
+func TestWebhookNotify(t *testing.T) {
+	requests := make([]webhookRequest, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, webhookRequest{method: r.Method, headers: r.Header, body: body})
+	}))
+	defer server.Close()
+
+	webhook, err := NewWebhook("webhook", WebhookConfig{Url: server.URL, Headers: map[string]string{"X-Token": "secret"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scope := "api"
+	firedAt := time.Now().UTC()
+	firing := Event{Status: StatusFiring, UUID: "a", Name: "Error rate", Scope: &scope, Description: "Error rate is 12%", FiredAt: &firedAt}
+
+	assert.Equal(t, webhook.Notify(firing), nil)
+
+	resolvedAt := firedAt.Add(time.Minute)
+	resolved := firing
+	resolved.Status = StatusResolved
+	resolved.ResolvedAt = &resolvedAt
+
+	assert.Equal(t, webhook.Notify(resolved), nil)
+	assert.Equal(t, len(requests), 2)
+
+	// The default payload is the event
+	assert.Equal(t, requests[0].method, "POST")
+	assert.Equal(t, requests[0].headers.Get("Content-Type"), "application/json")
+	assert.Equal(t, requests[0].headers.Get("X-Token"), "secret")
+
+	var payload Event
+	if err = json.Unmarshal(requests[0].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, payload.Status, StatusFiring)
+	assert.Equal(t, payload.UUID, "a")
+	assert.Equal(t, *payload.Scope, "api")
+	assert.Equal(t, payload.Description, "Error rate is 12%")
+	assert.Equal(t, payload.ResolvedAt == nil, true)
+
+	if err = json.Unmarshal(requests[1].body, &payload); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, payload.Status, StatusResolved)
+	assert.Equal(t, payload.ResolvedAt.Equal(resolvedAt), true)
+
+	// The custom template
+	method := "put"
+	template := `{"text": {{ json (printf "%s: %s" .Status .Title) }}}`
+	custom, err := NewWebhook("custom", WebhookConfig{Url: server.URL, Method: &method, Template: &template})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, custom.Notify(firing), nil)
+	assert.Equal(t, custom.Notify(resolved), nil)
+	assert.Equal(t, len(requests), 4)
+	assert.Equal(t, requests[2].method, "PUT")
+	assert.Equal(t, string(requests[2].body), `{"text": "firing: [API] Error rate"}`)
+	assert.Equal(t, string(requests[3].body), `{"text": "resolved: [API] Error rate"}`)
+
+	invalid := "{{ .Status"
+	_, err = NewWebhook("invalid", WebhookConfig{Url: server.URL, Template: &invalid})
+	assert.NotEqual(t, err, nil)
+}
+
+func TestWebhookCertificateVerification(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	event := Event{Status: StatusFiring, UUID: "a", Name: "Error rate"}
+
+	webhook, err := NewWebhook("webhook", WebhookConfig{Url: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.NotEqual(t, webhook.Notify(event), nil)
+
+	insecure, err := NewWebhook("insecure", WebhookConfig{Url: server.URL, InsecureSkipVerify: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, insecure.Notify(event), nil)
+}
@@ -0,0 +1,428 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+	"github.com/wavix/w-alerts/types"
+	"github.com/wavix/w-alerts/utils"
+)
+
+const (
+	MatchAll = "all"
+	MatchAny = "any"
+)
+
+const (
+	OperatorGt        = "gt"
+	OperatorGte       = "gte"
+	OperatorLt        = "lt"
+	OperatorLte       = "lte"
+	OperatorEq        = "eq" // Fires when the value is not equal, the value is the expected one
+	OperatorNe        = "ne" // Fires when the value is equal, the value is the forbidden one
+	OperatorIn        = "in"
+	OperatorNotIn     = "not_in"
+	OperatorBetween   = "between"
+	OperatorContains  = "contains"
+	OperatorRegex     = "regex"
+	OperatorExists    = "exists"
+	OperatorNotExists = "not_exists"
+)
+
+var operators = []string{
+	OperatorGt, OperatorGte, OperatorLt, OperatorLte, OperatorEq, OperatorNe, OperatorIn, OperatorNotIn,
+	OperatorBetween, OperatorContains, OperatorRegex, OperatorExists, OperatorNotExists,
+}
+
+// Compile validates the condition, the expressions are compiled once when the rule is loaded
+func (condition *RuleCondition) Compile() error {
+	condition.program = nil
+
+	if condition.IsGroup() {
+		if err := validateMatch(condition.Match); err != nil {
+			return err
+		}
+
+		for i := range condition.Rules {
+			if err := condition.Rules[i].Compile(); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if condition.Match != "" {
+		return errors.New("match is allowed only in the condition groups")
+	}
+
+	if condition.Expr == "" {
+		return condition.compileOperator()
+	}
+
+	program, err := expr.Compile(condition.Expr, expr.Env(types.RuleResponse{}), expr.AllowUndefinedVariables(), expr.AsBool())
+	if err != nil {
+		return fmt.Errorf("invalid expression '%s': %w", condition.Expr, err)
+	}
+
+	condition.program = program
+
+	return nil
+}
+
+// compileOperator validates the operator and its value
+func (condition *RuleCondition) compileOperator() error {
+	condition.pattern = nil
+
+	if condition.Operator == "" {
+		return nil
+	}
+
+	if !slices.Contains(operators, condition.Operator) {
+		return fmt.Errorf("unknown operator '%s'", condition.Operator)
+	}
+
+	hasThresholds := condition.Warning != nil || condition.Critical != nil
+	if condition.Value != nil || !hasThresholds {
+		if err := validateValue(condition.Operator, condition.Value); err != nil {
+			return err
+		}
+	}
+
+	for _, threshold := range []interface{}{condition.Warning, condition.Critical} {
+		if threshold == nil {
+			continue
+		}
+
+		switch condition.Operator {
+		case OperatorRegex, OperatorExists, OperatorNotExists:
+			return fmt.Errorf("operator '%s' doesn't support thresholds", condition.Operator)
+		}
+
+		if err := validateValue(condition.Operator, threshold); err != nil {
+			return err
+		}
+	}
+
+	if condition.ResolveValue != nil {
+		if err := condition.validateResolveValue(); err != nil {
+			return err
+		}
+	}
+
+	if condition.Operator == OperatorRegex {
+		pattern, err := regexp.Compile(condition.Value.(string))
+		if err != nil {
+			return fmt.Errorf("invalid regex '%s': %w", condition.Value, err)
+		}
+
+		condition.pattern = pattern
+	}
+
+	return nil
+}
+
+// validateResolveValue checks that resolve_value is on the resolved side of the fire thresholds
+// (ex: below the value of "gt")
+func (condition *RuleCondition) validateResolveValue() error {
+	resolveValue, ok := utils.ParseNumber(condition.ResolveValue)
+	if !ok {
+		return fmt.Errorf("resolve_value requires a number, got %v", condition.ResolveValue)
+	}
+
+	for _, threshold := range []interface{}{condition.Value, condition.Warning, condition.Critical} {
+		if threshold == nil {
+			continue
+		}
+
+		fire := utils.ToNumber(threshold)
+
+		switch condition.Operator {
+		case OperatorGt, OperatorGte:
+			if resolveValue > fire {
+				return fmt.Errorf("resolve_value %v must not be above the threshold %v", resolveValue, fire)
+			}
+		case OperatorLt, OperatorLte:
+			if resolveValue < fire {
+				return fmt.Errorf("resolve_value %v must not be below the threshold %v", resolveValue, fire)
+			}
+		default:
+			return fmt.Errorf("operator '%s' doesn't support resolve_value", condition.Operator)
+		}
+	}
+
+	return nil
+}
+
+func validateValue(operator string, value interface{}) error {
+	switch operator {
+	case OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+		if _, ok := utils.ParseNumber(value); !ok {
+			return fmt.Errorf("operator '%s' requires a number, got %v", operator, value)
+		}
+	case OperatorIn, OperatorNotIn:
+		if _, ok := value.([]interface{}); !ok {
+			return fmt.Errorf("operator '%s' requires a list of values", operator)
+		}
+	case OperatorBetween:
+		bounds, ok := value.([]interface{})
+		if !ok || len(bounds) != 2 {
+			return fmt.Errorf("operator '%s' requires [min, max]", operator)
+		}
+
+		for _, bound := range bounds {
+			if _, ok = utils.ParseNumber(bound); !ok {
+				return fmt.Errorf("operator '%s' requires numbers, got %v", operator, bound)
+			}
+		}
+	case OperatorRegex:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("operator '%s' requires a string", operator)
+		}
+	}
+
+	return nil
+}
+
+// IsGroup reports whether the condition is a group of the nested conditions
+func (condition *RuleCondition) IsGroup() bool {
+	return len(condition.Rules) > 0
+}
+
+// Evaluate runs the expression over the response (ex: errors.doc_count / hits.total.value > 0.1)
+func (condition *RuleCondition) Evaluate(response types.RuleResponse) (bool, error) {
+	if condition.program == nil {
+		return false, fmt.Errorf("expression '%s' is not compiled", condition.Expr)
+	}
+
+	result, err := vm.Run(condition.program, response)
+	if err != nil {
+		return false, fmt.Errorf("error evaluating expression '%s': %w", condition.Expr, err)
+	}
+
+	isMet, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("expression '%s' returned %v, expected a boolean", condition.Expr, result)
+	}
+
+	return isMet, nil
+}
+
+// Check returns the level of the condition (the problem is detected when it's not ok) and the value of the condition.
+// The state is the level of the firing rule, the firing conditions with resolve_value keep their level until the value
+// crosses resolve_value. The value of a group is the list of the nested values
+func (condition *RuleCondition) Check(response types.RuleResponse, state Level) (Level, interface{}, error) {
+	level, value, err := condition.check(response, state)
+	if condition.Not {
+		level = levelOf(level == LevelOK)
+	}
+
+	return level, value, err
+}
+
+func (condition *RuleCondition) check(response types.RuleResponse, state Level) (Level, interface{}, error) {
+	if condition.IsGroup() {
+		levels := make([]Level, len(condition.Rules))
+		values := make([]interface{}, len(condition.Rules))
+		errs := make([]error, 0)
+
+		for index := range condition.Rules {
+			level, value, err := condition.Rules[index].Check(response, state)
+			if err != nil {
+				errs = append(errs, err)
+			}
+
+			levels[index] = level
+			values[index] = value
+		}
+
+		return matchConditions(condition.Match, levels), values, errors.Join(errs...)
+	}
+
+	if condition.Expr != "" {
+		isMet, err := condition.Evaluate(response)
+		if err != nil {
+			return LevelOK, nil, err
+		}
+
+		return levelOf(isMet), isMet, nil
+	}
+
+	// "status" field check
+	if condition.Status != 0 {
+		value := utils.ToNumber(response["status"])
+		return levelOf(value != utils.ToNumber(condition.Status)), value, nil
+	}
+
+	if condition.Operator == OperatorExists || condition.Operator == OperatorNotExists {
+		value, ok := utils.GetValueFromMap(response, condition.Field)
+		exists := ok && value != nil
+
+		return levelOf(exists == (condition.Operator == OperatorExists)), value, nil
+	}
+
+	value, err := condition.value(response)
+	if err != nil {
+		return LevelOK, nil, err
+	}
+
+	level, err := condition.level(value)
+	if err != nil {
+		return level, value, err
+	}
+
+	// Hysteresis: the condition which fires the rule is not resolved until the value crosses resolve_value
+	if level == LevelOK && state != LevelOK && condition.lastLevel != LevelOK && condition.ResolveValue != nil {
+		isMet, err := condition.compare(value, condition.ResolveValue)
+		if err != nil {
+			return level, value, err
+		}
+
+		if isMet {
+			level = condition.lastLevel
+		}
+	}
+
+	condition.lastLevel = level
+
+	return level, value, nil
+}
+
+// level compares the value with the critical threshold (or the value of the condition) and the warning threshold
+func (condition *RuleCondition) level(value interface{}) (Level, error) {
+	critical := condition.Critical
+	if critical == nil {
+		critical = condition.Value
+	}
+
+	if critical != nil || condition.Warning == nil {
+		isMet, err := condition.compare(value, critical)
+		if err != nil || isMet {
+			return levelOf(isMet), err
+		}
+	}
+
+	if condition.Warning != nil {
+		isMet, err := condition.compare(value, condition.Warning)
+		if err != nil {
+			return LevelOK, err
+		}
+
+		if isMet {
+			return LevelWarning, nil
+		}
+	}
+
+	return LevelOK, nil
+}
+
+// compare applies the operator to the value and the expected value. The numeric operators coerce
+// the numeric strings and the booleans, the rest compare the values with utils.Equal
+func (condition *RuleCondition) compare(value interface{}, expected interface{}) (bool, error) {
+	switch condition.Operator {
+	case OperatorGt, OperatorGte, OperatorLt, OperatorLte:
+		number, ok := utils.ParseNumber(value)
+		if !ok {
+			return false, fmt.Errorf("field '%s': %v is not a number", condition.Field, value)
+		}
+
+		threshold := utils.ToNumber(expected)
+		switch condition.Operator {
+		case OperatorGt:
+			return number > threshold, nil
+		case OperatorGte:
+			return number >= threshold, nil
+		case OperatorLt:
+			return number < threshold, nil
+		default:
+			return number <= threshold, nil
+		}
+	case OperatorBetween:
+		number, ok := utils.ParseNumber(value)
+		if !ok {
+			return false, fmt.Errorf("field '%s': %v is not a number", condition.Field, value)
+		}
+
+		bounds := expected.([]interface{})
+		return number >= utils.ToNumber(bounds[0]) && number <= utils.ToNumber(bounds[1]), nil
+	case OperatorEq, OperatorNe:
+		return utils.Equal(value, expected) == (condition.Operator == OperatorNe), nil
+	case OperatorIn, OperatorNotIn:
+		isIn := slices.ContainsFunc(expected.([]interface{}), func(item interface{}) bool {
+			return utils.Equal(value, item)
+		})
+
+		return isIn == (condition.Operator == OperatorIn), nil
+	case OperatorContains:
+		if items, ok := value.([]interface{}); ok {
+			return slices.ContainsFunc(items, func(item interface{}) bool {
+				return utils.Equal(item, expected)
+			}), nil
+		}
+
+		if value == nil {
+			return false, nil
+		}
+
+		return strings.Contains(utils.ToString(value), utils.ToString(expected)), nil
+	case OperatorRegex:
+		if value == nil || condition.pattern == nil {
+			return false, nil
+		}
+
+		return condition.pattern.MatchString(utils.ToString(value)), nil
+	}
+
+	return false, nil
+}
+
+// value returns the field of the response, with field2 it's a ratio: field/field2
+func (condition *RuleCondition) value(response types.RuleResponse) (interface{}, error) {
+	value, ok := utils.GetValueFromMap(response, condition.Field)
+	if !ok {
+		return nil, fmt.Errorf("%w: field '%s' is missing in the response", ErrNoData, condition.Field)
+	}
+
+	if condition.Field2 == "" {
+		return value, nil
+	}
+
+	value2, ok := utils.GetValueFromMap(response, condition.Field2)
+	if !ok {
+		return nil, fmt.Errorf("%w: field2 '%s' is missing in the response", ErrNoData, condition.Field2)
+	}
+
+	valueNumber2 := utils.ToNumber(value2)
+	if valueNumber2 == 0 {
+		return 0, nil
+	}
+
+	// round to 2 decimal places
+	return float64(int((utils.ToNumber(value)/valueNumber2)*100)) / 100, nil
+}
+
+// matchConditions returns the lowest level of the conditions for "all" and the highest one for "any"
+func matchConditions(match string, levels []Level) Level {
+	if len(levels) == 0 {
+		return LevelOK
+	}
+
+	if match == MatchAny {
+		return slices.Max(levels)
+	}
+
+	return slices.Min(levels)
+}
+
+func validateMatch(match string) error {
+	if match != "" && match != MatchAll && match != MatchAny {
+		return fmt.Errorf("unsupported match '%s', expected '%s' or '%s'", match, MatchAll, MatchAny)
+	}
+
+	return nil
+}
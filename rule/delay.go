@@ -0,0 +1,108 @@
+package rule
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Delay is a duration ("5m") or a number of consecutive evaluations (3)
+type Delay struct {
+	Duration    time.Duration
+	Evaluations int
+}
+
+func (delay *Delay) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	var evaluations int
+	if err := json.Unmarshal(data, &evaluations); err == nil {
+		if evaluations < 0 {
+			return fmt.Errorf("invalid number of evaluations %d", evaluations)
+		}
+
+		*delay = Delay{Evaluations: evaluations}
+		return nil
+	}
+
+	var value string
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("delay must be a duration or a number of evaluations, got %s", data)
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid delay '%s': %w", value, err)
+	}
+
+	*delay = Delay{Duration: duration}
+
+	return nil
+}
+
+func (delay Delay) MarshalJSON() ([]byte, error) {
+	if delay.Evaluations > 0 {
+		return json.Marshal(delay.Evaluations)
+	}
+
+	return json.Marshal(delay.Duration.String())
+}
+
+// Reached reports whether the condition has held long enough since the first evaluation
+func (delay *Delay) Reached(since time.Time, evaluations int, now time.Time) bool {
+	if delay == nil {
+		return true
+	}
+
+	if delay.Evaluations > 0 {
+		return evaluations >= delay.Evaluations
+	}
+
+	return now.Sub(since) >= delay.Duration
+}
+
+func (rule *Rule) fireAfter() *Delay {
+	if rule.FireAfter != nil {
+		return rule.FireAfter
+	}
+
+	return rule.For
+}
+
+// settle delays the state change until the new state holds for fire_after (or resolve_after).
+// It returns the state of the rule after the evaluation
+func (rule *Rule) settle(isFire bool, now time.Time) bool {
+	if isFire == rule.IsFire {
+		rule.PendingSince = nil
+		rule.PendingEvaluations = 0
+		return isFire
+	}
+
+	delay := rule.ResolveAfter
+	if isFire {
+		delay = rule.fireAfter()
+	}
+
+	if rule.PendingSince == nil {
+		rule.PendingSince = &now
+		rule.PendingEvaluations = 0
+	}
+	rule.PendingEvaluations++
+
+	if !delay.Reached(*rule.PendingSince, rule.PendingEvaluations, now) {
+		return rule.IsFire
+	}
+
+	rule.PendingSince = nil
+	rule.PendingEvaluations = 0
+
+	return isFire
+}
+
+// IsPending reports whether the rule is waiting for fire_after before firing
+func (rule *Rule) IsPending() bool {
+	return !rule.IsFire && rule.PendingSince != nil
+}
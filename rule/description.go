@@ -0,0 +1,108 @@
+package rule
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/wavix/w-alerts/types"
+	"github.com/wavix/w-alerts/utils"
+)
+
+// DescriptionData is available in the description templates (ex: "Error rate is {{ percent .Values.errors }}")
+type DescriptionData struct {
+	UUID     string
+	Name     string
+	Scope    string
+	File     string
+	Severity string
+	IsFire   bool
+	FiredAt  *time.Time
+
+	// Values of the conditions by the condition name and by "condition_<N>"
+	Values   map[string]interface{}
+	Results  []interface{}
+	Response types.RuleResponse
+}
+
+// CompileDescription parses the description template. The descriptions without "{{"
+// keep the positional "{}" placeholders. A missing map key is an error, so a missing
+// value is not rendered as "<no value>"
+func (rule *Rule) CompileDescription() error {
+	rule.description = nil
+
+	if !strings.Contains(rule.Description, "{{") {
+		return nil
+	}
+
+	tmpl, err := template.New(rule.Name).Option("missingkey=error").Funcs(utils.TemplateFuncs).Parse(rule.Description)
+	if err != nil {
+		return fmt.Errorf("error parsing description template: %w", err)
+	}
+
+	rule.description = tmpl
+
+	return nil
+}
+
+// Values returns the results of the conditions by the condition name and by "condition_<N>".
+// The named conditions of the groups are available by their names
+func (rule *Rule) Values() map[string]interface{} {
+	values := make(map[string]interface{}, len(rule.RulesResults))
+
+	for index, value := range rule.RulesResults {
+		values[fmt.Sprintf("condition_%d", index+1)] = value
+	}
+
+	namedValues(rule.Rules, rule.RulesResults, values)
+
+	return values
+}
+
+func namedValues(conditions []RuleCondition, results []interface{}, values map[string]interface{}) {
+	for index, value := range results {
+		if index >= len(conditions) {
+			return
+		}
+
+		condition := conditions[index]
+		if condition.Name != "" {
+			values[condition.Name] = value
+		}
+
+		if nested, ok := value.([]interface{}); ok && condition.IsGroup() {
+			namedValues(condition.Rules, nested, values)
+		}
+	}
+}
+
+func (rule *Rule) RenderDescription() string {
+	if rule.description == nil {
+		return utils.ReplacePlaceholders(rule.Description, rule.RulesResults)
+	}
+
+	data := DescriptionData{
+		UUID:     rule.UUID,
+		Name:     rule.Name,
+		File:     rule.File,
+		Severity: rule.Severity,
+		IsFire:   rule.IsFire,
+		FiredAt:  rule.FiredAt,
+		Values:   rule.Values(),
+		Results:  rule.RulesResults,
+		Response: rule.Response,
+	}
+
+	if rule.Scope != nil {
+		data.Scope = *rule.Scope
+	}
+
+	var description strings.Builder
+	if err := rule.description.Execute(&description, data); err != nil {
+		utils.Logger.Context(rule.Name).Error().Msgf("Error rendering description: %v", err)
+		return rule.Description
+	}
+
+	return description.String()
+}
@@ -0,0 +1,123 @@
+package rule
+
+import (
+	"errors"
+	"time"
+
+	"github.com/wavix/w-alerts/notifier"
+)
+
+// FlapDetection overrides the global flap detection settings for the rule
+type FlapDetection struct {
+	Enabled       *bool    `json:"enabled"`
+	History       *int     `json:"history"`        // Number of the last evaluations
+	LowThreshold  *float64 `json:"low_threshold"`  // Percent of the state changes which stops the flapping
+	HighThreshold *float64 `json:"high_threshold"` // Percent of the state changes which starts the flapping
+}
+
+type FlapSettings struct {
+	Enabled       bool
+	History       int
+	LowThreshold  float64
+	HighThreshold float64
+}
+
+// FlapDefaults are the global flap detection settings, the thresholds are the Nagios defaults
+var FlapDefaults = FlapSettings{
+	Enabled:       false,
+	History:       21,
+	LowThreshold:  5,
+	HighThreshold: 20,
+}
+
+func (settings FlapSettings) Validate() error {
+	if settings.History < 3 {
+		return errors.New("flap detection history must be at least 3 evaluations")
+	}
+
+	if settings.LowThreshold < 0 || settings.HighThreshold > 100 || settings.LowThreshold > settings.HighThreshold {
+		return errors.New("flap detection thresholds must be 0 <= low <= high <= 100")
+	}
+
+	return nil
+}
+
+func (rule *Rule) flapSettings() FlapSettings {
+	settings := FlapDefaults
+	if rule.FlapDetection == nil {
+		return settings
+	}
+
+	if rule.FlapDetection.Enabled != nil {
+		settings.Enabled = *rule.FlapDetection.Enabled
+	}
+
+	if rule.FlapDetection.History != nil {
+		settings.History = *rule.FlapDetection.History
+	}
+
+	if rule.FlapDetection.LowThreshold != nil {
+		settings.LowThreshold = *rule.FlapDetection.LowThreshold
+	}
+
+	if rule.FlapDetection.HighThreshold != nil {
+		settings.HighThreshold = *rule.FlapDetection.HighThreshold
+	}
+
+	return settings
+}
+
+// FlapScore returns the weighted percent of the state changes in the history like Nagios does:
+// the weight grows from 0.8 for the oldest change to 1.2 for the newest one.
+// The missing evaluations of a short history are considered unchanged
+func FlapScore(history []bool, size int) float64 {
+	if size < 3 || len(history) < 2 {
+		return 0
+	}
+
+	offset := size - len(history)
+	changes := 0.0
+
+	for i := 1; i < len(history); i++ {
+		if history[i] != history[i-1] {
+			position := float64(offset + i - 1)
+			changes += 0.8 + 0.4*position/float64(size-2)
+		}
+	}
+
+	return changes / float64(size-1) * 100
+}
+
+// detectFlapping records the result of the evaluation and updates the flapping state
+func (rule *Rule) detectFlapping(isFire bool) {
+	settings := rule.flapSettings()
+	if !settings.Enabled {
+		rule.StateHistory = nil
+		rule.FlapScore = 0
+		rule.IsFlapping = false
+		return
+	}
+
+	rule.StateHistory = append(rule.StateHistory, isFire)
+	if len(rule.StateHistory) > settings.History {
+		rule.StateHistory = rule.StateHistory[len(rule.StateHistory)-settings.History:]
+	}
+
+	rule.FlapScore = FlapScore(rule.StateHistory, settings.History)
+
+	if !rule.IsFlapping && rule.FlapScore >= settings.HighThreshold {
+		rule.IsFlapping = true
+	} else if rule.IsFlapping && rule.FlapScore < settings.LowThreshold {
+		rule.IsFlapping = false
+	}
+}
+
+// notifyFlapping sends a single firing notification when the rule starts flapping
+func (rule *Rule) notifyFlapping(now time.Time) {
+	event := rule.NotificationEvent(now)
+	event.Status = notifier.StatusFiring
+	event.ResolvedAt = nil
+	event.Flapping = true
+
+	notifier.Announce(event)
+}
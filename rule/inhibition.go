@@ -0,0 +1,131 @@
+package rule
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/wavix/w-alerts/notifier"
+)
+
+// InhibitRule mutes the target rules while a source rule is firing.
+// The fields from Equal must have the same values in the source and the target
+type InhibitRule struct {
+	Source notifier.Matchers `json:"source"`
+	Target notifier.Matchers `json:"target"`
+	Equal  []string          `json:"equal"`
+}
+
+// InhibitedBy returns the firing rule which inhibits the rule
+func (registry *Registry) InhibitedBy(rule *Rule) *Rule {
+	if len(registry.InhibitRules) == 0 {
+		return nil
+	}
+
+	return registry.inhibitedBy(rule.MatchEvent())
+}
+
+func (registry *Registry) inhibitedBy(target notifier.Event) *Rule {
+	for _, inhibitRule := range registry.InhibitRules {
+		if !inhibitRule.Target.Matches(target) {
+			continue
+		}
+
+		for _, source := range registry.Rules {
+			if source.UUID == target.UUID || !source.IsFire {
+				continue
+			}
+
+			sourceEvent := source.MatchEvent()
+			if inhibitRule.Source.Matches(sourceEvent) && inhibitRule.isEqual(sourceEvent, target) {
+				return source
+			}
+		}
+	}
+
+	return nil
+}
+
+// Inhibitor resolves the inhibition of the event when it's dispatched, see notifier.SetInhibitor
+func (registry *Registry) Inhibitor(event notifier.Event) *string {
+	registry.Mutex.RLock()
+	defer registry.Mutex.RUnlock()
+
+	if len(registry.InhibitRules) == 0 {
+		return nil
+	}
+
+	if source := registry.inhibitedBy(event); source != nil {
+		uuid := source.UUID
+		return &uuid
+	}
+
+	return nil
+}
+
+// CheckInhibition updates the inhibition of the rule
+func (registry *Registry) CheckInhibition(rule *Rule) {
+	rule.InhibitedBy = nil
+
+	if source := registry.InhibitedBy(rule); source != nil {
+		uuid := source.UUID
+		rule.InhibitedBy = &uuid
+	}
+}
+
+// CheckInhibitions updates the inhibition of all the rules once their states are changed
+func (registry *Registry) CheckInhibitions() {
+	for _, rule := range registry.Rules {
+		registry.CheckInhibition(rule)
+	}
+}
+
+func (inhibitRule *InhibitRule) isEqual(source notifier.Event, target notifier.Event) bool {
+	sourceFields := source.MatchFields()
+	targetFields := target.MatchFields()
+
+	for _, field := range inhibitRule.Equal {
+		if sourceFields[field] != targetFields[field] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// LoadInhibitRules reads the inhibition rules
+func (registry *Registry) LoadInhibitRules(path string) error {
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var inhibitRules []InhibitRule
+	err = json.Unmarshal(jsonBytes, &inhibitRules)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling inhibit rules: %w", err)
+	}
+
+	for i := range inhibitRules {
+		if err = inhibitRules[i].Source.Compile(); err != nil {
+			return fmt.Errorf("inhibit rule %d source: %w", i+1, err)
+		}
+
+		if err = inhibitRules[i].Target.Compile(); err != nil {
+			return fmt.Errorf("inhibit rule %d target: %w", i+1, err)
+		}
+
+		for _, field := range inhibitRules[i].Equal {
+			if !slices.Contains(notifier.MatchFields, field) {
+				return fmt.Errorf("inhibit rule %d: unsupported equal field '%s'", i+1, field)
+			}
+		}
+	}
+
+	registry.Mutex.Lock()
+	registry.InhibitRules = inhibitRules
+	registry.Mutex.Unlock()
+
+	return nil
+}
@@ -0,0 +1,197 @@
+package rule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/wavix/w-alerts/notifier"
+	"github.com/wavix/w-alerts/utils"
+)
+
+const (
+	MaintenanceSkip     = "skip"     // The rule is not executed
+	MaintenanceEvaluate = "evaluate" // The rule is executed, but its state is not changed
+)
+
+var weekdays = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+
+// MaintenanceWindow is a recurring period when the rule legitimately misbehaves.
+// The window is set either by a cron expression with a duration or by the
+// weekdays with a time range. The matchers are used only by the global windows
+type MaintenanceWindow struct {
+	notifier.Matchers
+	Name     string   `json:"name"`
+	Cron     string   `json:"cron"`
+	Duration string   `json:"duration"`
+	Weekdays []string `json:"weekdays"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Timezone string   `json:"timezone"`
+	Mode     string   `json:"mode"`
+
+	location *time.Location
+	schedule *utils.CronSchedule
+	duration time.Duration
+	start    int
+	end      int
+}
+
+func (window *MaintenanceWindow) Compile() error {
+	if window.Mode == "" {
+		window.Mode = MaintenanceSkip
+	}
+
+	if window.Mode != MaintenanceSkip && window.Mode != MaintenanceEvaluate {
+		return fmt.Errorf("unsupported maintenance mode '%s'", window.Mode)
+	}
+
+	var err error
+	window.location = time.UTC
+	if window.Timezone != "" {
+		if window.location, err = time.LoadLocation(window.Timezone); err != nil {
+			return fmt.Errorf("invalid maintenance timezone: %w", err)
+		}
+	}
+
+	if err = window.Matchers.Compile(); err != nil {
+		return err
+	}
+
+	if window.Cron != "" {
+		if window.schedule, err = utils.ParseCron(window.Cron); err != nil {
+			return err
+		}
+
+		if window.duration, err = time.ParseDuration(window.Duration); err != nil || window.duration < time.Minute {
+			return errors.New("maintenance duration of at least 1m is required with cron")
+		}
+
+		return nil
+	}
+
+	for _, weekday := range window.Weekdays {
+		if !slices.Contains(weekdays, strings.ToLower(weekday)) {
+			return fmt.Errorf("invalid maintenance weekday '%s'", weekday)
+		}
+	}
+
+	if window.start, err = parseClock(window.Start); err != nil {
+		return fmt.Errorf("invalid maintenance start: %w", err)
+	}
+
+	if window.end, err = parseClock(window.End); err != nil {
+		return fmt.Errorf("invalid maintenance end: %w", err)
+	}
+
+	if window.start == window.end {
+		return errors.New("maintenance start and end must differ")
+	}
+
+	return nil
+}
+
+func (window *MaintenanceWindow) IsActive(now time.Time) bool {
+	now = now.In(window.location)
+
+	if window.schedule != nil {
+		// The window is active if it was started by the schedule within the duration
+		limit := now.Truncate(time.Minute).Add(time.Minute - window.duration)
+		_, started := window.schedule.Previous(now, limit)
+
+		return started
+	}
+
+	minutes := now.Hour()*60 + now.Minute()
+
+	if window.start < window.end {
+		return window.hasWeekday(now) && minutes >= window.start && minutes < window.end
+	}
+
+	// The window goes over midnight (ex: 23:00-02:00)
+	return (window.hasWeekday(now) && minutes >= window.start) ||
+		(window.hasWeekday(now.AddDate(0, 0, -1)) && minutes < window.end)
+}
+
+func (window *MaintenanceWindow) hasWeekday(t time.Time) bool {
+	if len(window.Weekdays) == 0 {
+		return true
+	}
+
+	weekday := weekdays[t.Weekday()]
+	for _, day := range window.Weekdays {
+		if strings.ToLower(day) == weekday {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ActiveMaintenance returns the active maintenance window of the rule
+// or the global window which matches the rule
+func (registry *Registry) ActiveMaintenance(rule *Rule, now time.Time) *MaintenanceWindow {
+	for i := range rule.Maintenance {
+		if rule.Maintenance[i].IsActive(now) {
+			return &rule.Maintenance[i]
+		}
+	}
+
+	event := rule.MatchEvent()
+	for i := range registry.Maintenance {
+		window := &registry.Maintenance[i]
+		if window.IsActive(now) && window.Matches(event) {
+			return window
+		}
+	}
+
+	return nil
+}
+
+// CheckMaintenance marks the rule which is in the "evaluate" maintenance window
+// and reports whether the rule must be skipped
+func (registry *Registry) CheckMaintenance(rule *Rule, now time.Time) bool {
+	window := registry.ActiveMaintenance(rule, now)
+	rule.InMaintenance = window != nil && window.Mode == MaintenanceEvaluate
+
+	return window != nil && window.Mode == MaintenanceSkip
+}
+
+// LoadMaintenance reads the global maintenance windows
+func (registry *Registry) LoadMaintenance(path string) error {
+	jsonBytes, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var windows []MaintenanceWindow
+	err = json.Unmarshal(jsonBytes, &windows)
+	if err != nil {
+		return fmt.Errorf("error unmarshalling maintenance windows: %w", err)
+	}
+
+	for i := range windows {
+		if err = windows[i].Compile(); err != nil {
+			return fmt.Errorf("maintenance window '%s': %w", windows[i].Name, err)
+		}
+	}
+
+	registry.Mutex.Lock()
+	registry.Maintenance = windows
+	registry.Mutex.Unlock()
+
+	return nil
+}
+
+func parseClock(value string) (int, error) {
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got '%s'", value)
+	}
+
+	return clock.Hour()*60 + clock.Minute(), nil
+}
@@ -0,0 +1,78 @@
+package rule
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/wavix/w-alerts/utils"
+)
+
+// ErrNoData is returned by the conditions when the field is missing in the response
+var ErrNoData = errors.New("no data")
+
+const (
+	DataStateNoData = "no_data"
+	DataStateError  = "error"
+)
+
+// Policies of the no_data_state and error_state settings
+const (
+	PolicyKeepLast = "keep_last" // The rule keeps its state, it's marked as stale (default)
+	PolicyOK       = "ok"        // The rule is resolved
+	PolicyAlerting = "alerting"  // The rule fires with the critical level
+	PolicyNoData   = "no_data"   // The rule gets the no_data (or error) status without notifications
+)
+
+var policies = []string{PolicyKeepLast, PolicyOK, PolicyAlerting, PolicyNoData}
+
+func validatePolicy(name string, policy string) error {
+	if policy != "" && !slices.Contains(policies, policy) {
+		return fmt.Errorf("unsupported %s '%s'", name, policy)
+	}
+
+	return nil
+}
+
+// RecordError handles the failed request of the rule with the error_state policy
+func (rule *Rule) RecordError(err error) {
+	rule.applyDataState(DataStateError, rule.ErrorState, err)
+}
+
+// applyDataState marks the rule without the data (or with the error) and applies the policy
+func (rule *Rule) applyDataState(state string, policy string, err error) {
+	now := time.Now().UTC()
+	message := err.Error()
+
+	rule.DataState = state
+	rule.LastError = &message
+	rule.LastErrorAt = &now
+
+	log := utils.Logger.Context(rule.Name)
+	log.Extra("data_state", state)
+	log.Error().Msgf("%v", err)
+
+	switch policy {
+	case PolicyOK:
+		rule.ToggleFire(ToggleFire{IsFire: false, RulesResults: rule.RulesResults, Response: rule.Response})
+	case PolicyAlerting:
+		rule.ToggleFire(ToggleFire{IsFire: true, Level: LevelCritical, RulesResults: rule.RulesResults, Response: rule.Response})
+	default:
+		// The next evaluation is delayed by the interval as after the successful one
+		rule.LastExecuted = &now
+	}
+}
+
+// IsStale reports whether the last evaluation of the rule has no data or has failed
+func (rule *Rule) IsStale() bool {
+	return rule.DataState != ""
+}
+
+func (rule *Rule) dataStatePolicy() string {
+	if rule.DataState == DataStateError {
+		return rule.ErrorState
+	}
+
+	return rule.NoDataState
+}
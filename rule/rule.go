@@ -2,12 +2,17 @@ package rule
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
+	"text/template"
 	"time"
 
+	"github.com/expr-lang/expr/vm"
+	"github.com/wavix/w-alerts/notifier"
 	"github.com/wavix/w-alerts/types"
 	"github.com/wavix/w-alerts/utils"
 
@@ -19,21 +24,67 @@ type Rule struct {
 	File         string     `json:"file"`
 	LastExecuted *time.Time `json:"last_executed"`
 	IsFire       bool       `json:"is_fire"`
-
-	Name        string          `json:"name"`
-	Scope       *string         `json:"scope"`
-	Description string          `json:"description"`
-	Index       string          `json:"index"`
-	Period      string          `json:"period"`
-	Interval    string          `json:"interval"`
-	Request     RuleRequest     `json:"request"`
-	Rules       []RuleCondition `json:"rules"`
+	FiredAt      *time.Time `json:"fired_at"`
+
+	// Level of the firing rule, the resolved rule keeps the last one
+	Level Level `json:"level"`
+
+	// The state change waits for fire_after or resolve_after since the first evaluation with the new state
+	PendingSince       *time.Time `json:"pending_since"`
+	PendingEvaluations int        `json:"pending_evaluations"`
+
+	// The results of the last evaluations for the flap detection
+	StateHistory []bool  `json:"state_history"`
+	FlapScore    float64 `json:"flap_score"`
+	IsFlapping   bool    `json:"is_flapping"`
+
+	// The last evaluation has no data or has failed, the last error is kept after the recovery
+	DataState   string     `json:"data_state"`
+	LastError   *string    `json:"last_error"`
+	LastErrorAt *time.Time `json:"last_error_at"`
+
+	Ack *types.Acknowledgement `json:"ack"`
+
+	Name        string              `json:"name"`
+	Scope       *string             `json:"scope"`
+	Description string              `json:"description"`
+	Index       string              `json:"index"`
+	Period      string              `json:"period"`
+	Interval    string              `json:"interval"`
+	Request     RuleRequest         `json:"request"`
+	Rules       []RuleCondition     `json:"rules"`
+	Match       string              `json:"match"` // all (by default) or any of the conditions fire the rule
+	Severity    string              `json:"severity"`
+	OptIn       []string            `json:"opt_in"` // Notification channels enabled only on demand (ex: sms)
+	Maintenance []MaintenanceWindow `json:"maintenance"`
+	Escalation  string              `json:"escalation"` // Name of the escalation policy
+
+	For          *Delay `json:"for"`        // Alias of fire_after
+	FireAfter    *Delay `json:"fire_after"` // The rule fires when the conditions are met for the duration or N evaluations
+	ResolveAfter *Delay `json:"resolve_after"`
+
+	FlapDetection *FlapDetection `json:"flap_detection"`
+
+	NoDataState string `json:"no_data_state"` // Policy when a field is missing: keep_last (default), ok, alerting, no_data
+	ErrorState  string `json:"error_state"`   // Policy when the request fails: keep_last (default), ok, alerting, no_data
 
 	RulesResults []interface{} `json:"rules_results"`
 
+	// The last response of the request, available in the description template
+	Response types.RuleResponse `json:"-"`
+
+	// The rule is in an active maintenance window with the "evaluate" mode,
+	// so the results are updated, but the state is not changed
+	InMaintenance bool `json:"in_maintenance"`
+
+	// UUID of the firing rule which inhibits the notifications of this rule
+	InhibitedBy *string `json:"inhibited_by"`
+
 	// Rules added by api for display alerts in /status
 	// it's not in the config file and none-logical alert
 	IsStaticAlert bool
+
+	description *template.Template
 }
 
 type RuleRequest struct {
@@ -42,16 +93,33 @@ type RuleRequest struct {
 }
 
 type RuleCondition struct {
+	Name     string      `json:"name"` // Name of the value in the description template
 	Field    string      `json:"field"`
 	Field2   string      `json:"field2"` // If exists, then it's a ratio: Field/Field2 for elastic request
 	Operator string      `json:"operator"`
 	Value    interface{} `json:"value"`
-	Status   int         `json:"status"`
+	Warning  interface{} `json:"warning"`  // Warning threshold
+	Critical interface{} `json:"critical"` // Critical threshold, the value is used by default
+	// The firing condition is resolved only when the value crosses resolve_value (ex: fire above 0.1, resolve below 0.05)
+	ResolveValue interface{} `json:"resolve_value"`
+	Status       int         `json:"status"`
+	Expr         string      `json:"expr"` // Boolean expression over the response, replaces the field and the operator
+
+	// Group of the nested conditions
+	Match string          `json:"match"`
+	Rules []RuleCondition `json:"rules"`
+	Not   bool            `json:"not"` // Negates the condition or the group
+
+	program   *vm.Program
+	pattern   *regexp.Regexp
+	lastLevel Level // Level of the last evaluation, the hysteresis holds only the firing condition
 }
 
 type Registry struct {
-	Rules map[string]*Rule
-	Mutex sync.RWMutex
+	Rules        map[string]*Rule
+	Maintenance  []MaintenanceWindow
+	InhibitRules []InhibitRule
+	Mutex        sync.RWMutex
 }
 
 type HttpRequest struct {
@@ -63,6 +131,7 @@ type HttpRequest struct {
 
 type ToggleFire struct {
 	IsFire       bool
+	Level        Level // Critical by default for the firing rules
 	Response     types.RuleResponse
 	Extra        logger.ExtraData
 	RulesResults []interface{}
@@ -132,92 +201,68 @@ func (rule *Rule) GetIndex() string {
 }
 
 func (rule *Rule) ProcessResponse(response types.RuleResponse) {
-	conditionsCount := len(rule.Rules)
-	triggeredConditions := 0
-
-	if len(rule.Rules) > 0 {
-
-		rulesResults := make([]interface{}, conditionsCount)
-		extraData := logger.ExtraData{}
-
-		for index, ruleCondition := range rule.Rules {
-			var value interface{}
-
-			if ruleCondition.Field != "" {
-				var ok bool
-				value, ok = utils.GetValueFromMap(response, ruleCondition.Field)
-
-				if !ok {
-					utils.Logger.Context(rule.Name).Error().Msgf("Error getting value for field '%s' from response: %v", response, ruleCondition.Field)
-					continue
-				}
-
-				if !ok {
-					utils.Logger.Context(rule.Name).Error().Msgf("Error converting value to number: %v", value)
-					continue
-				}
-
-				// If there is a second field, then it's a ratio
-				// Field/Field2
-				if ruleCondition.Field2 != "" {
-					value2, ok := utils.GetValueFromMap(response, ruleCondition.Field2)
-					if !ok {
-						utils.Logger.Context(rule.Name).Error().Msgf("Error getting value for field2 '%s' from response: %v", response, ruleCondition.Field2)
-						continue
-					}
-
-					valueNumber2 := utils.ToNumber(value2)
-					if !ok {
-						utils.Logger.Context(rule.Name).Error().Msgf("Error converting value to number: %v", value)
-						continue
-					}
-
-					// round to 2 decimal places
-					val := utils.ToNumber(value)
-					value = float64(int((val/valueNumber2)*100)) / 100
-					if valueNumber2 == 0 {
-						value = 0
-					}
-				}
-			}
-
-			// "status" field check
-			if ruleCondition.Status != 0 {
-				value = utils.ToNumber(response["status"])
-
-				if value != utils.ToNumber(ruleCondition.Status) {
-					rulesResults[index] = value
-					rule.ToggleFire(ToggleFire{IsFire: true, Response: response, Extra: extraData, RulesResults: rulesResults})
-					return
-				}
-			}
-
-			// eq's fields check
-			if ruleCondition.Operator == "lt" && utils.ToNumber(value) < utils.ToNumber(ruleCondition.Value) {
-				triggeredConditions += 1
-			}
-
-			if ruleCondition.Operator == "gt" && utils.ToNumber(value) > utils.ToNumber(ruleCondition.Value) {
-				triggeredConditions += 1
-			}
-
-			if ruleCondition.Operator == "eq" && value != ruleCondition.Value {
-				triggeredConditions += 1
-			}
-
-			ruleId := fmt.Sprintf("condition_%d", index+1)
-			extraData[ruleId] = value
-			rulesResults[index] = value
+	if len(rule.Rules) == 0 {
+		return
+	}
+
+	rulesResults := make([]interface{}, len(rule.Rules))
+	levels := make([]Level, len(rule.Rules))
+	extraData := logger.ExtraData{}
+
+	state := LevelOK
+	if rule.IsFire {
+		state = rule.Level
+		if state == LevelOK {
+			state = LevelCritical
 		}
+	}
 
-		isFire := triggeredConditions == conditionsCount
-		rule.ToggleFire(ToggleFire{
-			IsFire:       isFire,
-			Response:     response,
-			Extra:        extraData,
-			RulesResults: rulesResults,
-		})
+	errs := make([]error, 0)
+
+	for index := range rule.Rules {
+		condition := &rule.Rules[index]
+
+		level, value, err := condition.Check(response, state)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		ruleId := fmt.Sprintf("condition_%d", index+1)
+		extraData[ruleId] = value
+		rulesResults[index] = value
+		levels[index] = level
+
+		// Without the explicit match mode the failed status check fires the rule immediately
+		if rule.Match == "" && condition.Status != 0 && level != LevelOK {
+			rule.DataState = ""
+			rule.ToggleFire(ToggleFire{IsFire: true, Response: response, Extra: extraData, RulesResults: rulesResults})
+			return
+		}
+	}
+
+	level := matchConditions(rule.Match, levels)
+
+	// The errors decide the result only when the rest of the conditions don't fire the rule:
+	// any of the failed conditions with "all" and no met conditions with "any"
+	if err := errors.Join(errs...); err != nil && (rule.Match != MatchAny || level == LevelOK) {
+		if errors.Is(err, ErrNoData) {
+			rule.applyDataState(DataStateNoData, rule.NoDataState, err)
+		} else {
+			rule.applyDataState(DataStateError, rule.ErrorState, err)
+		}
+
+		return
 	}
+
+	rule.DataState = ""
+
+	rule.ToggleFire(ToggleFire{
+		IsFire:       level != LevelOK,
+		Level:        level,
+		Response:     response,
+		Extra:        extraData,
+		RulesResults: rulesResults,
+	})
 }
 
 func (rule *Rule) ToggleFire(params ToggleFire) {
@@ -226,21 +271,97 @@ func (rule *Rule) ToggleFire(params ToggleFire) {
 
 	rule.LastExecuted = &now
 
+	if rule.InMaintenance {
+		rule.RulesResults = params.RulesResults
+		rule.Response = params.Response
+
+		log := utils.Logger.Context(rule.Name, params.Extra)
+		log.Extra("fire", params.IsFire)
+		log.Extra("maintenance", true)
+		log.Info().Msgf("%v", params.Response)
+		return
+	}
+
+	if !params.IsFire {
+		params.Level = LevelOK
+	}
+
+	wasFlapping := rule.IsFlapping
+	rule.detectFlapping(params.IsFire)
+
+	params.IsFire = rule.settle(params.IsFire, now)
 	if params.IsFire != rule.IsFire {
 		isStatusChanged = true
 	}
 
+	// The rule waiting for resolve_after keeps its level
+	level := params.Level
+	if params.IsFire && level == LevelOK && rule.IsFire {
+		level = rule.Level
+	}
+
+	if params.IsFire && level == LevelOK {
+		level = LevelCritical
+	}
+
+	// The firing rule notifies about the level changes (ex: warning -> critical)
+	previousLevel := rule.Level
+	isLevelChanged := !isStatusChanged && params.IsFire && level != previousLevel
+
 	// In the case when the problem is resolved, we need to show the previous statistics in the description.
 	// Therefore, we change the list of results only when an isFired event has occurred or the status has not changed
 	if !isStatusChanged || (isStatusChanged && params.IsFire) {
 		rule.RulesResults = params.RulesResults
+		rule.Response = params.Response
 	}
 
 	rule.IsFire = params.IsFire
+	if params.IsFire {
+		rule.Level = level
+	}
+
+	// The acknowledgement is cleared when the problem is resolved
+	if isStatusChanged && !params.IsFire {
+		rule.Ack = nil
+	}
+
+	switch {
+	case rule.IsFlapping && !wasFlapping:
+		rule.notifyFlapping(now.UTC())
+	case rule.IsFlapping:
+		// The transitions are suppressed until the rule stabilizes
+		if isStatusChanged && rule.IsFire {
+			firedAt := now.UTC()
+			rule.FiredAt = &firedAt
+		}
+	case wasFlapping && rule.IsFire && notifier.IsFiring(rule.UUID):
+		// The rule stabilized firing, the receivers already got the firing notification before the flapping
+		notifier.Evaluate(rule.NotificationEvent(now))
+	case wasFlapping:
+		// The rule stabilized, the receivers get its current state
+		notifier.Notify(rule.NotificationEvent(now.UTC()))
+	case isStatusChanged:
+		rule.NotifyTransition()
+	case isLevelChanged:
+		event := rule.NotificationEvent(now.UTC())
+		event.PreviousSeverity = previousLevel.String()
+		notifier.Notify(event)
+	default:
+		notifier.Evaluate(rule.NotificationEvent(now))
+	}
 
 	log := utils.Logger.Context(rule.Name, params.Extra)
 	log.Extra("fire", params.IsFire)
 	log.Extra("state_changed", isStatusChanged)
+	if rule.PendingSince != nil {
+		log.Extra("pending_evaluations", rule.PendingEvaluations)
+	}
+	if rule.IsFlapping {
+		log.Extra("flapping", rule.FlapScore)
+	}
+	if params.IsFire {
+		log.Extra("level", level.String())
+	}
 
 	if params.IsFire {
 		log.Warn().Msgf("%v", params.Response)
@@ -250,6 +371,98 @@ func (rule *Rule) ToggleFire(params ToggleFire) {
 	log.Info().Msgf("%v", params.Response)
 }
 
+// SetFire changes the state of a static alert and notifies about the transition
+func (rule *Rule) SetFire(isFire bool) {
+	if rule.IsFire == isFire {
+		return
+	}
+
+	now := time.Now().UTC()
+	rule.IsFire = isFire
+	rule.LastExecuted = &now
+
+	if isFire {
+		rule.Level = LevelCritical
+	}
+
+	if !isFire {
+		rule.Ack = nil
+	}
+
+	rule.NotifyTransition()
+}
+
+// Acknowledge marks the firing rule as owned by someone, the repeat notifications
+// and escalations are suppressed until the rule is resolved or the ack expires
+func (rule *Rule) Acknowledge(ack types.Acknowledgement) {
+	rule.Ack = &ack
+	notifier.Acknowledge(rule.UUID, rule.Ack)
+}
+
+func (rule *Rule) Unacknowledge() {
+	rule.Ack = nil
+	notifier.Acknowledge(rule.UUID, nil)
+}
+
+// NotifyTransition sends the current state of the rule to the notification receivers
+func (rule *Rule) NotifyTransition() {
+	now := time.Now().UTC()
+
+	if rule.IsFire {
+		rule.FiredAt = &now
+	}
+
+	notifier.Notify(rule.NotificationEvent(now))
+}
+
+func (rule *Rule) NotificationEvent(timestamp time.Time) notifier.Event {
+	event := notifier.Event{
+		Status:       notifier.StatusResolved,
+		UUID:         rule.UUID,
+		Name:         rule.Name,
+		Scope:        rule.Scope,
+		Description:  rule.RenderDescription(),
+		RulesResults: rule.RulesResults,
+		Values:       rule.Values(),
+		File:         rule.File,
+		IsStatic:     rule.IsStaticAlert,
+		OptIn:        rule.OptIn,
+		Severity:     rule.EventSeverity(),
+		FiredAt:      rule.FiredAt,
+		Ack:          rule.Ack,
+		InhibitedBy:  rule.InhibitedBy,
+		Escalation:   rule.Escalation,
+		Timestamp:    timestamp,
+	}
+
+	if rule.IsFire {
+		event.Status = notifier.StatusFiring
+	} else {
+		event.ResolvedAt = &timestamp
+	}
+
+	return event
+}
+
+// MatchEvent returns the event with only the fields used by the matchers (see notifier.Event.MatchFields),
+// unlike NotificationEvent it doesn't render the description
+func (rule *Rule) MatchEvent() notifier.Event {
+	event := notifier.Event{
+		Status:   notifier.StatusResolved,
+		UUID:     rule.UUID,
+		Name:     rule.Name,
+		Scope:    rule.Scope,
+		File:     rule.File,
+		Severity: rule.EventSeverity(),
+	}
+
+	if rule.IsFire {
+		event.Status = notifier.StatusFiring
+	}
+
+	return event
+}
+
 func (rule *Rule) GetNextRunAt() time.Time {
 	if rule.LastExecuted == nil {
 		return time.Now().Add(-1 * time.Second)
@@ -269,6 +482,43 @@ func (rule *Rule) GetRule(path string) error {
 	rule.UUID = utils.GenerateRuleUUID(fileName, rule.Name)
 	rule.File = path
 
+	for i := range rule.Maintenance {
+		err := rule.Maintenance[i].Compile()
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := validateMatch(rule.Match); err != nil {
+		return err
+	}
+
+	if err := validatePolicy("no_data_state", rule.NoDataState); err != nil {
+		return err
+	}
+
+	if err := validatePolicy("error_state", rule.ErrorState); err != nil {
+		return err
+	}
+
+	if rule.FlapDetection != nil {
+		if err := rule.flapSettings().Validate(); err != nil {
+			return err
+		}
+	}
+
+	for i := range rule.Rules {
+		err := rule.Rules[i].Compile()
+		if err != nil {
+			return err
+		}
+	}
+
+	err := rule.CompileDescription()
+	if err != nil {
+		return err
+	}
+
 	if rule.Request.Elastic != nil {
 		rule.Request.Elastic["size"] = 0
 
@@ -292,14 +542,40 @@ func (registry *Registry) AddRule(rule Rule) {
 
 		// Preserve the current state
 		registry.Rules[rule.UUID].IsFire = current.IsFire
+		registry.Rules[rule.UUID].FiredAt = current.FiredAt
+		registry.Rules[rule.UUID].Level = current.Level
+		registry.Rules[rule.UUID].PendingSince = current.PendingSince
+		registry.Rules[rule.UUID].PendingEvaluations = current.PendingEvaluations
+		registry.Rules[rule.UUID].StateHistory = current.StateHistory
+		registry.Rules[rule.UUID].FlapScore = current.FlapScore
+		registry.Rules[rule.UUID].IsFlapping = current.IsFlapping
+		registry.Rules[rule.UUID].DataState = current.DataState
+		registry.Rules[rule.UUID].LastError = current.LastError
+		registry.Rules[rule.UUID].LastErrorAt = current.LastErrorAt
+		registry.Rules[rule.UUID].Ack = current.Ack
 		registry.Rules[rule.UUID].RulesResults = current.RulesResults
+		registry.Rules[rule.UUID].Response = current.Response
 		registry.Rules[rule.UUID].LastExecuted = current.LastExecuted
+		preserveLevels(registry.Rules[rule.UUID].Rules, current.Rules)
 		return
 	}
 
 	registry.Rules[rule.UUID] = &rule
 }
 
+// preserveLevels keeps the levels of the last evaluation of the reloaded conditions,
+// so the hysteresis still holds the firing condition
+func preserveLevels(conditions []RuleCondition, current []RuleCondition) {
+	for i := range conditions {
+		if i >= len(current) {
+			return
+		}
+
+		conditions[i].lastLevel = current[i].lastLevel
+		preserveLevels(conditions[i].Rules, current[i].Rules)
+	}
+}
+
 func (registry *Registry) RemoveRule(uuid string) {
 	registry.Mutex.Lock()
 	defer registry.Mutex.Unlock()
@@ -352,6 +628,10 @@ func (registry *Registry) LoadStaticRules() {
 	}
 
 	for _, r := range staticRules {
+		if err = r.CompileDescription(); err != nil {
+			utils.Logger.Context(r.Name).Error().Msgf("Error loading static rule: %v", err)
+		}
+
 		registry.AddRule(r)
 	}
 }
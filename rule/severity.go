@@ -0,0 +1,97 @@
+package rule
+
+import "fmt"
+
+// Level is the severity of a firing rule computed from the warning and critical thresholds
+type Level int
+
+const (
+	LevelOK Level = iota
+	LevelWarning
+	LevelCritical
+)
+
+var levelNames = []string{"ok", "warning", "critical"}
+
+func (level Level) String() string {
+	if level < LevelOK || level > LevelCritical {
+		return levelNames[LevelOK]
+	}
+
+	return levelNames[level]
+}
+
+func (level Level) MarshalText() ([]byte, error) {
+	return []byte(level.String()), nil
+}
+
+func (level *Level) UnmarshalText(text []byte) error {
+	for index, name := range levelNames {
+		if name == string(text) {
+			*level = Level(index)
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unknown level '%s'", text)
+}
+
+// levelOf returns the critical level for the met conditions without thresholds
+func levelOf(isMet bool) Level {
+	if isMet {
+		return LevelCritical
+	}
+
+	return LevelOK
+}
+
+// HasThresholds reports whether the rule has the warning thresholds
+func (rule *Rule) HasThresholds() bool {
+	return hasWarning(rule.Rules)
+}
+
+func hasWarning(conditions []RuleCondition) bool {
+	for _, condition := range conditions {
+		if condition.Warning != nil || hasWarning(condition.Rules) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Status returns the level of the rule for the API: ok, pending, warning, critical, flapping,
+// or no_data and error for the rules with the no_data policy
+func (rule *Rule) Status() string {
+	if rule.IsFlapping {
+		return "flapping"
+	}
+
+	if rule.IsStale() && rule.dataStatePolicy() == PolicyNoData {
+		return rule.DataState
+	}
+
+	if rule.IsPending() {
+		return "pending"
+	}
+
+	if !rule.IsFire {
+		return LevelOK.String()
+	}
+
+	if rule.Level == LevelWarning {
+		return LevelWarning.String()
+	}
+
+	return LevelCritical.String()
+}
+
+// EventSeverity returns the severity of the notifications. The rules with the thresholds
+// use their level, the other rules use the configured severity
+func (rule *Rule) EventSeverity() string {
+	if rule.HasThresholds() && rule.Level != LevelOK {
+		return rule.Level.String()
+	}
+
+	return rule.Severity
+}
@@ -1,3 +1,22 @@
 package types
 
+import "time"
+
 type RuleResponse = map[string]interface{}
+
+// Acknowledgement marks that someone owns a firing alert
+type Acknowledgement struct {
+	By        string     `json:"by"`
+	Comment   string     `json:"comment"`
+	At        time.Time  `json:"at"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// IsActive reports whether the acknowledgement exists and is not expired
+func (ack *Acknowledgement) IsActive() bool {
+	if ack == nil {
+		return false
+	}
+
+	return ack.ExpiresAt == nil || ack.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseNumber converts the value to a number. The numbers, the numeric strings
+// and the booleans (true is 1, false is 0) are converted, null and other values are not
+func ParseNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case json.Number:
+		number, err := v.Float64()
+		return number, err == nil
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		number, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return number, err == nil
+	}
+
+	return 0, false
+}
+
+// ParseBool converts the booleans and the "true"/"false" strings
+func ParseBool(value interface{}) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case string:
+		parsed, err := strconv.ParseBool(strings.TrimSpace(v))
+		return parsed, err == nil
+	}
+
+	return false, false
+}
+
+// ToString formats the value for the string comparisons, the numbers without trailing zeros
+func ToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	}
+
+	if number, ok := ParseNumber(value); ok {
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		return string(data)
+	}
+
+	return fmt.Sprintf("%v", value)
+}
+
+// Equal compares the values with the type coercion: null is equal only to null, the booleans are compared
+// with the booleans and "true"/"false", the numbers with the numbers and the numeric strings, the rest as strings
+func Equal(a interface{}, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+
+	_, isBoolA := a.(bool)
+	_, isBoolB := b.(bool)
+	if isBoolA || isBoolB {
+		boolA, okA := ParseBool(a)
+		boolB, okB := ParseBool(b)
+		return okA && okB && boolA == boolB
+	}
+
+	numberA, okA := ParseNumber(a)
+	numberB, okB := ParseNumber(b)
+	if okA && okB {
+		return numberA == numberB
+	}
+
+	return ToString(a) == ToString(b)
+}
@@ -0,0 +1,172 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed 5-field cron expression (minute hour day-of-month month day-of-week).
+// Each field supports *, lists (1,2), ranges (1-5) and steps (*/15, 1-30/5).
+// As in Vixie cron, when both day-of-month and day-of-week are restricted
+// (don't start with *), the day matches either of them
+type CronSchedule struct {
+	fields  [5]map[int]struct{}
+	anyDay  bool // day-of-month starts with *
+	anyWeek bool // day-of-week starts with *
+}
+
+var cronBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 6}}
+
+func ParseCron(expression string) (*CronSchedule, error) {
+	parts := strings.Fields(expression)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("cron expression must have 5 fields: '%s'", expression)
+	}
+
+	schedule := &CronSchedule{}
+	for index, part := range parts {
+		values, err := parseCronField(part, cronBounds[index][0], cronBounds[index][1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron field '%s': %w", part, err)
+		}
+
+		schedule.fields[index] = values
+	}
+
+	schedule.anyDay = strings.HasPrefix(parts[2], "*")
+	schedule.anyWeek = strings.HasPrefix(parts[4], "*")
+
+	return schedule, nil
+}
+
+// Matches reports whether the minute of the time matches the schedule
+func (schedule *CronSchedule) Matches(t time.Time) bool {
+	if !schedule.matchesDay(t) {
+		return false
+	}
+
+	_, minute := schedule.fields[0][t.Minute()]
+	_, hour := schedule.fields[1][t.Hour()]
+
+	return minute && hour
+}
+
+// Previous returns the last time at or before t which matches the schedule,
+// false if there is no match between limit and t
+func (schedule *CronSchedule) Previous(t time.Time, limit time.Time) (time.Time, bool) {
+	t = t.Truncate(time.Minute)
+	hours := descending(schedule.fields[1])
+	minutes := descending(schedule.fields[0])
+
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	first := time.Date(limit.Year(), limit.Month(), limit.Day(), 0, 0, 0, 0, t.Location())
+
+	for ; !day.Before(first); day = day.AddDate(0, 0, -1) {
+		if !schedule.matchesDay(day) {
+			continue
+		}
+
+		for _, hour := range hours {
+			for _, minute := range minutes {
+				match := time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, t.Location())
+				if match.After(t) {
+					continue
+				}
+
+				if match.Before(limit) {
+					return time.Time{}, false
+				}
+
+				return match, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func (schedule *CronSchedule) matchesDay(t time.Time) bool {
+	if _, ok := schedule.fields[3][int(t.Month())]; !ok {
+		return false
+	}
+
+	_, day := schedule.fields[2][t.Day()]
+	_, weekday := schedule.fields[4][int(t.Weekday())]
+
+	if schedule.anyDay || schedule.anyWeek {
+		return day && weekday
+	}
+
+	return day || weekday
+}
+
+func descending(values map[int]struct{}) []int {
+	sorted := make([]int, 0, len(values))
+	for value := range values {
+		sorted = append(sorted, value)
+	}
+
+	slices.Sort(sorted)
+	slices.Reverse(sorted)
+
+	return sorted
+}
+
+func parseCronField(field string, min int, max int) (map[int]struct{}, error) {
+	values := make(map[int]struct{})
+
+	for _, item := range strings.Split(field, ",") {
+		step := 1
+		if index := strings.Index(item, "/"); index != -1 {
+			var err error
+			step, err = strconv.Atoi(item[index+1:])
+			if err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step '%s'", item)
+			}
+			item = item[:index]
+		}
+
+		from, to := min, max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+
+			var err error
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid value '%s'", item)
+			}
+
+			to = from
+			if len(bounds) == 2 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return nil, fmt.Errorf("invalid value '%s'", item)
+				}
+			}
+		}
+
+		// Sunday can be set as 7 in the day-of-week field
+		limit := max
+		if max == 6 {
+			limit = 7
+		}
+
+		if from < min || to > limit || from > to {
+			return nil, fmt.Errorf("value out of range '%s'", item)
+		}
+
+		for value := from; value <= to; value += step {
+			key := value
+			if max == 6 && key == 7 {
+				key = 0
+			}
+
+			values[key] = struct{}{}
+		}
+	}
+
+	return values, nil
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TemplateFuncs are the helper functions available in the rule descriptions and the notification templates
+var TemplateFuncs = map[string]interface{}{
+	"round":    Round,
+	"percent":  Percent,
+	"duration": HumanizeDuration,
+	"bytes":    HumanizeBytes,
+}
+
+// Round rounds the number to the decimal places (0 by default)
+func Round(value interface{}, places ...int) float64 {
+	precision := 0
+	if len(places) > 0 {
+		precision = places[0]
+	}
+
+	factor := math.Pow(10, float64(precision))
+	return math.Round(ToNumber(value)*factor) / factor
+}
+
+// Percent formats the ratio as a percentage (ex: 0.256 -> 25.6%), 2 decimal places by default
+func Percent(value interface{}, places ...int) string {
+	precision := 2
+	if len(places) > 0 {
+		precision = places[0]
+	}
+
+	return formatNumber(Round(ToNumber(value)*100, precision)) + "%"
+}
+
+// HumanizeDuration formats the number of seconds or the duration (ex: 3725 -> 1h 2m 5s)
+func HumanizeDuration(value interface{}) string {
+	var duration time.Duration
+	switch v := value.(type) {
+	case time.Duration:
+		duration = v
+	case string:
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return v
+		}
+		duration = parsed
+	default:
+		duration = time.Duration(ToNumber(value) * float64(time.Second))
+	}
+
+	sign := ""
+	if duration < 0 {
+		sign = "-"
+		duration = -duration
+	}
+
+	if duration < time.Second {
+		return sign + duration.Round(time.Millisecond).String()
+	}
+
+	units := []struct {
+		suffix string
+		size   time.Duration
+	}{
+		{"d", 24 * time.Hour},
+		{"h", time.Hour},
+		{"m", time.Minute},
+		{"s", time.Second},
+	}
+
+	parts := make([]string, 0, len(units))
+	for _, unit := range units {
+		if count := duration / unit.size; count > 0 {
+			parts = append(parts, fmt.Sprintf("%d%s", count, unit.suffix))
+			duration -= count * unit.size
+		}
+	}
+
+	return sign + strings.Join(parts, " ")
+}
+
+// HumanizeBytes formats the size in bytes with the binary units (ex: 1536 -> 1.5 KiB)
+func HumanizeBytes(value interface{}) string {
+	size := ToNumber(value)
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB", "PiB"}
+
+	index := 0
+	for math.Abs(size) >= 1024 && index < len(units)-1 {
+		size /= 1024
+		index++
+	}
+
+	return formatNumber(Round(size, 2)) + " " + units[index]
+}
+
+func formatNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
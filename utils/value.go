@@ -35,19 +35,10 @@ func GetValueFromMap(m map[string]interface{}, keyPath string) (interface{}, boo
 	return value, true
 }
 
+// ToNumber converts the value with ParseNumber, 0 for the values which are not numbers
 func ToNumber(value interface{}) float64 {
-	var result float64
-
-	switch v := value.(type) {
-	case int:
-		result = float64(v)
-	case float64:
-		result = v
-	default:
-		return 0
-	}
-
-	return result
+	number, _ := ParseNumber(value)
+	return number
 }
 
 func IsString(value interface{}) bool {
@@ -91,3 +82,12 @@ func ReplacePlaceholders(template string, values []interface{}) string {
 
 	return result.String()
 }
+
+// ScopedName returns the name prefixed with the upper-cased scope (ex: [API] name)
+func ScopedName(name string, scope *string) string {
+	if scope == nil || *scope == "" {
+		return name
+	}
+
+	return fmt.Sprintf("[%s] %s", strings.ToUpper(*scope), name)
+}